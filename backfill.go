@@ -0,0 +1,173 @@
+package bonk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/gosolana/ws"
+)
+
+const (
+	signaturesPageLimit = 1000 // getSignaturesForAddress 单页最大数量
+	backfillConcurrency = 8    // 补齐时并发获取交易的数量
+)
+
+// Cursor 最后一笔收到的交易签名和slot
+func (c *Client) Cursor() (solana.Signature, uint64) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lastSignature, c.lastSlot
+}
+
+// SetCursor 设置最后一笔收到的交易, 例如从持久化的记录中恢复, 启动后会先补齐之后的交易
+func (c *Client) SetCursor(signature solana.Signature, slot uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lastSignature = signature
+	c.lastSlot = slot
+}
+
+// markSeen 记录收到的交易, slot 只前进不后退
+func (c *Client) markSeen(msg *ws.LogResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if msg.Context.Slot >= c.lastSlot {
+		c.lastSignature = msg.Value.Signature
+		c.lastSlot = msg.Context.Slot
+	}
+}
+
+// rpcClient 获取 http rpc 客户端, 设置了共享的 RPC 时使用它
+func (c *Client) rpcClient() *rpc.Client {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return rpcClient(c.RPC, c.RpcClient)
+}
+
+// backfillCommitment getSignaturesForAddress 和 getTransaction 不支持 processed
+func backfillCommitment(commit rpc.CommitmentType) rpc.CommitmentType {
+	if commit == rpc.CommitmentProcessed || commit == "" {
+		return rpc.CommitmentConfirmed
+	}
+	return commit
+}
+
+// backfill 补齐最后一笔交易之后断线期间遗漏的交易, 按时间顺序写入日志队列
+//
+// 返回补齐的交易数量, 没有记录过交易时不做任何处理; 某笔交易重试后仍然获取失败时停在该交易之前,
+// 游标不会越过它, 下次重连时从这里继续补齐, 已经分发过的交易由 Dedup 过滤
+func (c *Client) backfill(ctx context.Context, pubKey solana.PublicKey, commit rpc.CommitmentType) (int, error) {
+	until, _ := c.Cursor()
+	if until.IsZero() {
+		return 0, nil
+	}
+	commit = backfillCommitment(commit)
+
+	signatures, err := c.missedSignatures(ctx, pubKey, until, commit)
+	if err != nil {
+		return 0, err
+	}
+
+	// getSignaturesForAddress 从新到旧返回, 转换为从旧到新
+	for i, j := 0, len(signatures)-1; i < j; i, j = i+1, j-1 {
+		signatures[i], signatures[j] = signatures[j], signatures[i]
+	}
+
+	count := 0
+	for start := 0; start < len(signatures); start += backfillConcurrency {
+		end := min(start+backfillConcurrency, len(signatures))
+		results := c.fetchLogResults(ctx, signatures[start:end], commit)
+		for i, msg := range results {
+			if msg == nil {
+				return count, fmt.Errorf("补齐交易 %s 失败, 之后的交易在下次重连时补齐", signatures[start+i].Signature)
+			}
+			ok, err := c.enqueue(ctx, msg, nil)
+			if err != nil {
+				return count, err
+			}
+			if ok {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// missedSignatures 分页获取 until 之后的全部交易签名, 最多 BackfillLimit 笔
+func (c *Client) missedSignatures(ctx context.Context, pubKey solana.PublicKey, until solana.Signature, commit rpc.CommitmentType) ([]*rpc.TransactionSignature, error) {
+	var (
+		result []*rpc.TransactionSignature
+		before solana.Signature
+	)
+	for {
+		// 最后一页只请求不足上限的部分, 结果不会超过 BackfillLimit
+		limit := signaturesPageLimit
+		if c.BackfillLimit > 0 {
+			limit = min(limit, c.BackfillLimit-len(result))
+		}
+		page, err := c.rpcClient().GetSignaturesForAddressWithOpts(ctx, pubKey, &rpc.GetSignaturesForAddressOpts{
+			Limit:      &limit,
+			Before:     before,
+			Until:      until,
+			Commitment: commit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取交易签名失败: %w", err)
+		}
+		result = append(result, page...)
+		if len(page) < limit {
+			return result, nil
+		}
+		if c.BackfillLimit > 0 && len(result) >= c.BackfillLimit {
+			log.Printf("断线期间的交易达到补齐上限 %d, 只补齐最近的交易", c.BackfillLimit)
+			return result, nil
+		}
+		before = page[len(page)-1].Signature
+	}
+}
+
+// fetchLogResults 并发获取交易日志, 返回结果与 signatures 顺序一致, 获取失败的为 nil
+//
+// 签名已经可以查询时交易也可能还没有被 getTransaction 索引, 不存在时按 DefaultTransactionRetry 重试
+func (c *Client) fetchLogResults(ctx context.Context, signatures []*rpc.TransactionSignature, commit rpc.CommitmentType) []*ws.LogResult {
+	fetcher := &transactionFetcher{
+		client:     c.rpcClient(),
+		commitment: commit,
+		policy:     DefaultTransactionRetry,
+		stats:      &transactionStats{},
+	}
+	results := make([]*ws.LogResult, len(signatures))
+	var wg sync.WaitGroup
+	for i, signature := range signatures {
+		if signature.Err != nil || (c.Dedup != nil && c.Dedup.Contains(signature.Signature)) {
+			// 失败或已经处理过的交易不需要日志, 只用于推进游标
+			msg := &ws.LogResult{}
+			msg.Context.Slot = signature.Slot
+			msg.Value.Signature = signature.Signature
+			msg.Value.Err = signature.Err
+			results[i] = msg
+			continue
+		}
+		wg.Add(1)
+		go func(i int, signature *rpc.TransactionSignature) {
+			defer wg.Done()
+			transaction, err := fetcher.fetch(ctx, signature.Signature)
+			if err != nil {
+				log.Printf("补齐交易 %s 失败 | %v", signature.Signature, err)
+				return
+			}
+			msg := &ws.LogResult{}
+			msg.Context.Slot = transaction.Slot
+			msg.Value.Signature = signature.Signature
+			msg.Value.Err = transaction.Meta.Err
+			msg.Value.Logs = transaction.Meta.LogMessages
+			results[i] = msg
+		}(i, signature)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,70 @@
+package bonk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/gosolana/ws"
+)
+
+func TestBackfillStopsAtFailedTransaction(t *testing.T) {
+	newSignature := func() solana.Signature {
+		var signature solana.Signature
+		copy(signature[:], solana.NewWallet().PublicKey().Bytes())
+		return signature
+	}
+	var (
+		cursor = newSignature()
+		first  = newSignature()
+		failed = newSignature()
+		last   = newSignature()
+	)
+	slots := map[string]uint64{first.String(): 11, failed.String(): 12, last.String(): 13}
+
+	inner := newFakeJSONRPC(func(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+		switch method {
+		case "getSignaturesForAddress":
+			// 从新到旧返回
+			return []map[string]any{
+				{"signature": last.String(), "slot": 13},
+				{"signature": failed.String(), "slot": 12},
+				{"signature": first.String(), "slot": 11},
+			}, nil
+		case "getTransaction":
+			signature := params[0].(solana.Signature).String()
+			if signature == failed.String() {
+				return nil, errors.New("connection reset")
+			}
+			return map[string]any{
+				"slot":        slots[signature],
+				"transaction": []string{"AQ==", "base64"},
+				"meta":        map[string]any{"err": nil, "logMessages": []string{"Program log: " + signature}},
+			}, nil
+		}
+		return nil, errors.New("unexpected method " + method)
+	})
+	c := &Client{
+		RpcClient: rpc.NewWithCustomRPCClient(inner),
+		LogQuque:  make(chan *ws.LogResult, 10),
+		Dedup:     NewSignatureDedup(100, 0),
+	}
+	c.SetCursor(cursor, 10)
+
+	count, err := c.backfill(context.Background(), solana.SystemProgramID, rpc.CommitmentConfirmed)
+	if err == nil {
+		t.Fatal("backfill() error = nil")
+	}
+	if count != 1 || len(c.LogQuque) != 1 {
+		t.Fatalf("backfill() = %d, queued %d, want 1", count, len(c.LogQuque))
+	}
+	if msg := <-c.LogQuque; !msg.Value.Signature.Equals(first) {
+		t.Errorf("queued %s, want %s", msg.Value.Signature, first)
+	}
+	// 游标停在获取失败的交易之前, 下次补齐时重新获取
+	if signature, slot := c.Cursor(); !signature.Equals(first) || slot != 11 {
+		t.Errorf("Cursor() = %s, %d, want %s, 11", signature, slot, first)
+	}
+}
@@ -0,0 +1,99 @@
+package bonk
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// instructionRef 交易中的一条指令(外层或CPI)
+type instructionRef struct {
+	Index        int              // 外层指令索引
+	InnerIndex   int              // inner instruction 索引, 外层指令为 -1
+	Depth        int              // 调用深度, 外层指令为 1, CPI 从 2 开始, 0 表示未知
+	OuterProgram solana.PublicKey // 外层指令调用的程序, 例如聚合器路由
+	Instruction  solana.CompiledInstruction
+}
+
+// parseInvokeDepth 解析 "Program <id> invoke [n]" 日志中的调用深度
+func parseInvokeDepth(logMsg string) (int, bool) {
+	if _, ok := parseProgramInvoke(logMsg); !ok {
+		return 0, false
+	}
+	start := strings.LastIndex(logMsg, "[")
+	end := strings.LastIndex(logMsg, "]")
+	if start < 0 || end <= start {
+		return 0, false
+	}
+	depth, err := strconv.Atoi(logMsg[start+1 : end])
+	if err != nil {
+		return 0, false
+	}
+	return depth, true
+}
+
+// innerInstructionDepths 根据日志计算每条外层指令下CPI的调用深度
+//
+// 每一条 inner instruction 都对应一条 invoke [n>=2] 日志, 顺序一致
+func innerInstructionDepths(logs []string) map[int][]int {
+	result := make(map[int][]int)
+	index := -1
+	for _, logMsg := range logs {
+		depth, ok := parseInvokeDepth(logMsg)
+		if !ok {
+			continue
+		}
+		if depth == 1 {
+			index++
+			continue
+		}
+		if index >= 0 {
+			result[index] = append(result[index], depth)
+		}
+	}
+	return result
+}
+
+// flattenInstructions 按执行顺序展开交易中的外层指令以及CPI指令
+//
+// 日志被截断导致数量对不上时, CPI 的深度记为 0
+func flattenInstructions(transaction *solana.Transaction, meta *rpc.TransactionMeta) []*instructionRef {
+	inner := make(map[int][]solana.CompiledInstruction)
+	var depths map[int][]int
+	if meta != nil {
+		for _, group := range meta.InnerInstructions {
+			inner[int(group.Index)] = append(inner[int(group.Index)], group.Instructions...)
+		}
+		depths = innerInstructionDepths(meta.LogMessages)
+	}
+
+	var result []*instructionRef
+	for i, instruction := range transaction.Message.Instructions {
+		outerProgram, _ := transaction.Message.Program(instruction.ProgramIDIndex)
+		result = append(result, &instructionRef{
+			Index:        i,
+			InnerIndex:   -1,
+			Depth:        1,
+			OuterProgram: outerProgram,
+			Instruction:  instruction,
+		})
+
+		known := len(depths[i]) == len(inner[i])
+		for j, innerInstruction := range inner[i] {
+			depth := 0
+			if known {
+				depth = depths[i][j]
+			}
+			result = append(result, &instructionRef{
+				Index:        i,
+				InnerIndex:   j,
+				Depth:        depth,
+				OuterProgram: outerProgram,
+				Instruction:  innerInstruction,
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,281 @@
+package bonk
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+)
+
+// CurveType 价格曲线类型, 取值与 GlobalConfig.CurveType 一致
+type CurveType uint8
+
+const (
+	CurveType_Constant CurveType = iota // 恒定乘积曲线
+	CurveType_Fixed                     // 固定价格曲线
+	CurveType_Linear                    // 线性价格曲线
+)
+
+func (t CurveType) String() string {
+	switch t {
+	case CurveType_Constant:
+		return "Constant"
+	case CurveType_Fixed:
+		return "Fixed"
+	case CurveType_Linear:
+		return "Linear"
+	default:
+		return ""
+	}
+}
+
+// GetCurveType 获取 CurveParams 具体变体对应的曲线类型
+func GetCurveType(params raydium_launchpad.CurveParams) (CurveType, error) {
+	switch params.(type) {
+	case *raydium_launchpad.CurveParams_Constant:
+		return CurveType_Constant, nil
+	case *raydium_launchpad.CurveParams_Fixed:
+		return CurveType_Fixed, nil
+	case *raydium_launchpad.CurveParams_Linear:
+		return CurveType_Linear, nil
+	default:
+		return 0, fmt.Errorf("未知的曲线参数类型: %T", params)
+	}
+}
+
+// Q64 线性曲线斜率 virtual_base 使用的定点数精度
+var Q64 = new(big.Int).Lsh(big.NewInt(1), 64)
+
+var ErrInsufficientLiquidity = errors.New("池子流动性不足")
+
+// curveCalculator 曲线在不含手续费时的数学计算, 与链上程序一致
+//
+// 所有数量均为最小单位, base 为代币, quote 为报价代币(例如 WSOL)
+type curveCalculator interface {
+	buyExactIn(pool *raydium_launchpad.PoolState, quoteIn *big.Int) (*big.Int, error)
+	buyExactOut(pool *raydium_launchpad.PoolState, baseOut *big.Int) (*big.Int, error)
+	sellExactIn(pool *raydium_launchpad.PoolState, baseIn *big.Int) (*big.Int, error)
+	sellExactOut(pool *raydium_launchpad.PoolState, quoteOut *big.Int) (*big.Int, error)
+	// spotPrice 当前的边际价格, 每个最小单位的 base 对应的 quote 最小单位数量
+	spotPrice(pool *raydium_launchpad.PoolState) (*big.Float, error)
+}
+
+// SpotPrice 池子当前的价格, 每个 base 代币对应的 quote 代币数量, 已按小数位换算
+func SpotPrice(pool *raydium_launchpad.PoolState, curveType CurveType) (float64, error) {
+	calculator, err := getCurveCalculator(curveType)
+	if err != nil {
+		return 0, err
+	}
+	price, err := calculator.spotPrice(pool)
+	if err != nil {
+		return 0, err
+	}
+	price.Mul(price, pow10(pool.BaseDecimals))
+	price.Quo(price, pow10(pool.QuoteDecimals))
+	result, _ := price.Float64()
+	return result, nil
+}
+
+func pow10(decimals uint8) *big.Float {
+	return new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+}
+
+// getCurveCalculator 获取曲线类型对应的计算器
+func getCurveCalculator(curveType CurveType) (curveCalculator, error) {
+	switch curveType {
+	case CurveType_Constant:
+		return constantCurve{}, nil
+	case CurveType_Fixed:
+		return fixedCurve{}, nil
+	case CurveType_Linear:
+		return linearCurve{}, nil
+	default:
+		return nil, fmt.Errorf("未知的曲线类型: %d", curveType)
+	}
+}
+
+func u64(v uint64) *big.Int {
+	return new(big.Int).SetUint64(v)
+}
+
+// ceilDiv 向上取整的除法
+func ceilDiv(numerator, denominator *big.Int) *big.Int {
+	quo, rem := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if rem.Sign() != 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	return quo
+}
+
+// constantCurve 恒定乘积曲线, virtual_quote/virtual_base 为初始价格
+type constantCurve struct{}
+
+// getAmountOut amount_in * output_reserve / (input_reserve + amount_in), 向下取整
+func (constantCurve) getAmountOut(amountIn, inputReserve, outputReserve *big.Int) (*big.Int, error) {
+	numerator := new(big.Int).Mul(amountIn, outputReserve)
+	denominator := new(big.Int).Add(inputReserve, amountIn)
+	if denominator.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	return numerator.Quo(numerator, denominator), nil
+}
+
+// getAmountIn input_reserve * amount_out / (output_reserve - amount_out), 向上取整
+func (constantCurve) getAmountIn(amountOut, inputReserve, outputReserve *big.Int) (*big.Int, error) {
+	denominator := new(big.Int).Sub(outputReserve, amountOut)
+	if denominator.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	return ceilDiv(new(big.Int).Mul(inputReserve, amountOut), denominator), nil
+}
+
+func (c constantCurve) reserves(pool *raydium_launchpad.PoolState) (base, quote *big.Int) {
+	base = new(big.Int).Sub(u64(pool.VirtualBase), u64(pool.RealBase))
+	quote = new(big.Int).Add(u64(pool.VirtualQuote), u64(pool.RealQuote))
+	return base, quote
+}
+
+func (c constantCurve) spotPrice(pool *raydium_launchpad.PoolState) (*big.Float, error) {
+	base, quote := c.reserves(pool)
+	if base.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	return new(big.Float).Quo(new(big.Float).SetInt(quote), new(big.Float).SetInt(base)), nil
+}
+
+func (c constantCurve) buyExactIn(pool *raydium_launchpad.PoolState, quoteIn *big.Int) (*big.Int, error) {
+	base, quote := c.reserves(pool)
+	return c.getAmountOut(quoteIn, quote, base)
+}
+
+func (c constantCurve) buyExactOut(pool *raydium_launchpad.PoolState, baseOut *big.Int) (*big.Int, error) {
+	base, quote := c.reserves(pool)
+	return c.getAmountIn(baseOut, quote, base)
+}
+
+func (c constantCurve) sellExactIn(pool *raydium_launchpad.PoolState, baseIn *big.Int) (*big.Int, error) {
+	base, quote := c.reserves(pool)
+	return c.getAmountOut(baseIn, base, quote)
+}
+
+func (c constantCurve) sellExactOut(pool *raydium_launchpad.PoolState, quoteOut *big.Int) (*big.Int, error) {
+	base, quote := c.reserves(pool)
+	return c.getAmountIn(quoteOut, base, quote)
+}
+
+// fixedCurve 固定价格曲线, 价格为 virtual_quote/virtual_base
+type fixedCurve struct{}
+
+func (fixedCurve) check(pool *raydium_launchpad.PoolState) error {
+	if pool.VirtualBase == 0 || pool.VirtualQuote == 0 {
+		return errors.New("固定价格曲线的虚拟储备为0")
+	}
+	return nil
+}
+
+func (c fixedCurve) spotPrice(pool *raydium_launchpad.PoolState) (*big.Float, error) {
+	if err := c.check(pool); err != nil {
+		return nil, err
+	}
+	return new(big.Float).Quo(new(big.Float).SetUint64(pool.VirtualQuote), new(big.Float).SetUint64(pool.VirtualBase)), nil
+}
+
+func (c fixedCurve) buyExactIn(pool *raydium_launchpad.PoolState, quoteIn *big.Int) (*big.Int, error) {
+	if err := c.check(pool); err != nil {
+		return nil, err
+	}
+	out := new(big.Int).Mul(quoteIn, u64(pool.VirtualBase))
+	return out.Quo(out, u64(pool.VirtualQuote)), nil
+}
+
+func (c fixedCurve) buyExactOut(pool *raydium_launchpad.PoolState, baseOut *big.Int) (*big.Int, error) {
+	if err := c.check(pool); err != nil {
+		return nil, err
+	}
+	return ceilDiv(new(big.Int).Mul(baseOut, u64(pool.VirtualQuote)), u64(pool.VirtualBase)), nil
+}
+
+func (c fixedCurve) sellExactIn(pool *raydium_launchpad.PoolState, baseIn *big.Int) (*big.Int, error) {
+	if err := c.check(pool); err != nil {
+		return nil, err
+	}
+	out := new(big.Int).Mul(baseIn, u64(pool.VirtualQuote))
+	return out.Quo(out, u64(pool.VirtualBase)), nil
+}
+
+func (c fixedCurve) sellExactOut(pool *raydium_launchpad.PoolState, quoteOut *big.Int) (*big.Int, error) {
+	if err := c.check(pool); err != nil {
+		return nil, err
+	}
+	return ceilDiv(new(big.Int).Mul(quoteOut, u64(pool.VirtualBase)), u64(pool.VirtualQuote)), nil
+}
+
+// linearCurve 线性价格曲线, price = a * real_base, a = virtual_base / 2^64
+//
+// 已募集的 quote 等于曲线下的面积: real_quote = a * real_base^2 / 2
+type linearCurve struct{}
+
+// quoteAt 卖出 base 数量为 x 时曲线下的面积, 向上取整
+func (linearCurve) quoteAt(pool *raydium_launchpad.PoolState, base *big.Int) *big.Int {
+	numerator := new(big.Int).Mul(u64(pool.VirtualBase), new(big.Int).Mul(base, base))
+	return ceilDiv(numerator, new(big.Int).Lsh(Q64, 1))
+}
+
+// baseAt 募集 quote 数量为 y 时已卖出的 base, sqrt(2 * y * 2^64 / a) 向下取整
+func (linearCurve) baseAt(pool *raydium_launchpad.PoolState, quote *big.Int) (*big.Int, error) {
+	if pool.VirtualBase == 0 {
+		return nil, errors.New("线性曲线的斜率为0")
+	}
+	term := new(big.Int).Mul(new(big.Int).Lsh(quote, 1), Q64)
+	term.Quo(term, u64(pool.VirtualBase))
+	return term.Sqrt(term), nil
+}
+
+// spotPrice a * real_base
+func (c linearCurve) spotPrice(pool *raydium_launchpad.PoolState) (*big.Float, error) {
+	price := new(big.Float).SetInt(new(big.Int).Mul(u64(pool.VirtualBase), u64(pool.RealBase)))
+	return price.Quo(price, new(big.Float).SetInt(Q64)), nil
+}
+
+func (c linearCurve) buyExactIn(pool *raydium_launchpad.PoolState, quoteIn *big.Int) (*big.Int, error) {
+	newBase, err := c.baseAt(pool, new(big.Int).Add(u64(pool.RealQuote), quoteIn))
+	if err != nil {
+		return nil, err
+	}
+	out := newBase.Sub(newBase, u64(pool.RealBase))
+	if out.Sign() < 0 {
+		return big.NewInt(0), nil
+	}
+	return out, nil
+}
+
+func (c linearCurve) buyExactOut(pool *raydium_launchpad.PoolState, baseOut *big.Int) (*big.Int, error) {
+	newQuote := c.quoteAt(pool, new(big.Int).Add(u64(pool.RealBase), baseOut))
+	return newQuote.Sub(newQuote, u64(pool.RealQuote)), nil
+}
+
+func (c linearCurve) sellExactIn(pool *raydium_launchpad.PoolState, baseIn *big.Int) (*big.Int, error) {
+	newBase := new(big.Int).Sub(u64(pool.RealBase), baseIn)
+	if newBase.Sign() < 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	newQuote := c.quoteAt(pool, newBase)
+	out := new(big.Int).Sub(u64(pool.RealQuote), newQuote)
+	if out.Sign() < 0 {
+		return big.NewInt(0), nil
+	}
+	return out, nil
+}
+
+func (c linearCurve) sellExactOut(pool *raydium_launchpad.PoolState, quoteOut *big.Int) (*big.Int, error) {
+	newQuote := new(big.Int).Sub(u64(pool.RealQuote), quoteOut)
+	if newQuote.Sign() < 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	newBase, err := c.baseAt(pool, newQuote)
+	if err != nil {
+		return nil, err
+	}
+	return newBase.Sub(u64(pool.RealBase), newBase), nil
+}
@@ -0,0 +1,119 @@
+package bonk
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// DedupStats 去重统计
+type DedupStats struct {
+	Hits   uint64 `json:"hits"`   // 重复的签名数量
+	Misses uint64 `json:"misses"` // 第一次出现的签名数量
+	Size   int    `json:"size"`   // 当前记录的签名数量
+}
+
+type dedupEntry struct {
+	signature solana.Signature
+	seenAt    time.Time
+}
+
+// SignatureDedup 有界且带时间窗口的签名去重
+//
+// 最多记录 capacity 个签名, 超过 window 的记录会被淘汰, 两者都按先进先出淘汰
+type SignatureDedup struct {
+	lock     sync.Mutex
+	capacity int
+	window   time.Duration
+	seen     map[solana.Signature]time.Time
+	queue    []dedupEntry // 按记录时间排序
+	head     int
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func NewSignatureDedup(capacity int, window time.Duration) *SignatureDedup {
+	if capacity <= 0 {
+		capacity = 100_000
+	}
+	return &SignatureDedup{
+		capacity: capacity,
+		window:   window,
+		seen:     make(map[solana.Signature]time.Time),
+	}
+}
+
+// Seen 判断签名是否已经出现过, 第一次出现时记录并返回 false
+func (d *SignatureDedup) Seen(signature solana.Signature) bool {
+	_, duplicate := d.observe(signature)
+	return duplicate
+}
+
+// observe 记录签名, 返回第一次出现的时间以及是否重复
+func (d *SignatureDedup) observe(signature solana.Signature) (time.Time, bool) {
+	now := time.Now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.evict(now)
+	if seenAt, ok := d.seen[signature]; ok {
+		d.hits.Add(1)
+		return seenAt, true
+	}
+	d.misses.Add(1)
+	d.seen[signature] = now
+	d.queue = append(d.queue, dedupEntry{signature: signature, seenAt: now})
+	if len(d.seen) > d.capacity {
+		d.evictFront()
+	}
+	return now, false
+}
+
+// Contains 判断签名是否已经出现过, 不记录也不计入统计
+func (d *SignatureDedup) Contains(signature solana.Signature) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.evict(time.Now())
+	_, ok := d.seen[signature]
+	return ok
+}
+
+// Stats 获取去重统计
+func (d *SignatureDedup) Stats() DedupStats {
+	d.lock.Lock()
+	size := len(d.seen)
+	d.lock.Unlock()
+	return DedupStats{
+		Hits:   d.hits.Load(),
+		Misses: d.misses.Load(),
+		Size:   size,
+	}
+}
+
+// evict 淘汰超出时间窗口的记录
+func (d *SignatureDedup) evict(now time.Time) {
+	if d.window <= 0 {
+		return
+	}
+	for d.head < len(d.queue) && now.Sub(d.queue[d.head].seenAt) > d.window {
+		d.evictFront()
+	}
+}
+
+// evictFront 淘汰最早的一条记录
+func (d *SignatureDedup) evictFront() {
+	entry := d.queue[d.head]
+	d.queue[d.head] = dedupEntry{}
+	d.head++
+	if seenAt, ok := d.seen[entry.signature]; ok && seenAt.Equal(entry.seenAt) {
+		delete(d.seen, entry.signature)
+	}
+	// 已淘汰的部分超过一半时压缩队列, 避免底层数组无限增长
+	if d.head > len(d.queue)/2 {
+		d.queue = append(d.queue[:0:0], d.queue[d.head:]...)
+		d.head = 0
+	}
+}
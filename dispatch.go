@@ -0,0 +1,151 @@
+package bonk
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-enols/gosolana/ws"
+)
+
+// QueuePolicy 日志队列写满时的处理方式
+type QueuePolicy int
+
+const (
+	QueueBlock      QueuePolicy = iota // 阻塞订阅直到队列有空位, 不丢弃日志
+	QueueDropOldest                    // 丢弃队列中最早的日志, 保证处理最新的交易
+	QueueDropNewest                    // 丢弃新收到的日志
+)
+
+func (p QueuePolicy) String() string {
+	switch p {
+	case QueueBlock:
+		return "Block"
+	case QueueDropOldest:
+		return "DropOldest"
+	case QueueDropNewest:
+		return "DropNewest"
+	default:
+		return ""
+	}
+}
+
+// QueueStats 日志队列和中间件的统计
+type QueueStats struct {
+	Depth         int    `json:"depth"`          // 队列中等待分发的日志数量
+	Capacity      int    `json:"capacity"`       // 队列容量
+	InFlight      int    `json:"in_flight"`      // 正在运行的中间件数量
+	Workers       int    `json:"workers"`        // 同时运行的中间件数量上限, 0 表示不限制
+	DroppedOldest uint64 `json:"dropped_oldest"` // QueueDropOldest 丢弃的日志数量
+	DroppedNewest uint64 `json:"dropped_newest"` // QueueDropNewest 丢弃的日志数量
+}
+
+// dispatcher 日志队列的分发统计
+type dispatcher struct {
+	workers       chan struct{} // 中间件并发信号量, 第一次分发时按 Client.Workers 创建
+	once          sync.Once
+	wg            sync.WaitGroup // 正在运行的中间件以及 Ordered 中间件的协程
+	inFlight      atomic.Int64
+	droppedOldest atomic.Uint64
+	droppedNewest atomic.Uint64
+}
+
+// push 按 QueuePolicy 写入日志队列, 返回是否写入
+func (c *Client) push(ctx context.Context, msg *ws.LogResult) (bool, error) {
+	switch c.QueuePolicy {
+	case QueueDropNewest:
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case c.LogQuque <- msg:
+			return true, nil
+		default:
+			c.dispatch.droppedNewest.Add(1)
+			return false, nil
+		}
+	case QueueDropOldest:
+		for {
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case c.LogQuque <- msg:
+				return true, nil
+			default:
+			}
+			// 队列已满, 丢弃最早的一条后重试; 期间被分发协程取走时直接重试
+			select {
+			case <-c.LogQuque:
+				c.dispatch.droppedOldest.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case c.LogQuque <- msg:
+			return true, nil
+		}
+	}
+}
+
+// QueueStats 获取日志队列深度、丢弃数量以及正在运行的中间件数量
+func (c *Client) QueueStats() QueueStats {
+	return QueueStats{
+		Depth:         len(c.LogQuque),
+		Capacity:      cap(c.LogQuque),
+		InFlight:      int(c.dispatch.inFlight.Load()),
+		Workers:       max(c.Workers, 0),
+		DroppedOldest: c.dispatch.droppedOldest.Load(),
+		DroppedNewest: c.dispatch.droppedNewest.Load(),
+	}
+}
+
+// 将管道中的日志进行分发
+//
+// 每个中间件在独立的协程中运行(Ordered 中间件在各自的协程中逐条运行), 同时运行的数量不超过 Workers,
+// 达到上限时暂停分发, 队列写满后按 QueuePolicy 处理新的日志; 队列被 Close 关闭后处理完剩余的日志再退出
+func (c *Client) logProcess(ctx context.Context) {
+	defer close(c.processDone)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case log, ok := <-c.LogQuque:
+			if !ok {
+				c.closeOrderedQueues()
+				return
+			}
+			if !c.dispatchLog(ctx, log) {
+				return
+			}
+		}
+	}
+}
+
+// acquireWorker 占用一个中间件并发名额, ctx 结束时返回 false
+func (c *Client) acquireWorker(ctx context.Context) bool {
+	c.dispatch.once.Do(func() {
+		if c.Workers > 0 {
+			c.dispatch.workers = make(chan struct{}, c.Workers)
+		}
+	})
+	if c.dispatch.workers != nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case c.dispatch.workers <- struct{}{}:
+		}
+	}
+	c.dispatch.inFlight.Add(1)
+	c.dispatch.wg.Add(1)
+	return true
+}
+
+func (c *Client) releaseWorker() {
+	c.dispatch.inFlight.Add(-1)
+	c.dispatch.wg.Done()
+	if c.dispatch.workers != nil {
+		<-c.dispatch.workers
+	}
+}
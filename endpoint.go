@@ -0,0 +1,66 @@
+package bonk
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-enols/gosolana/ws"
+)
+
+// endpoint 一个 websocket 订阅端点
+type endpoint struct {
+	url    string
+	proxy  string
+	client *ws.Client
+	owns   bool // client 是否由 Client 创建, 只有自己创建的连接才会在重连时关闭
+	cancel context.CancelFunc
+	state  ConnState
+
+	notifications atomic.Uint64 // 收到的有效通知数量
+	wins          atomic.Uint64 // 最先到达的通知数量
+	reconnects    atomic.Uint64
+	delaySum      atomic.Int64 // 落后最先到达端点的时间总和, 纳秒
+	delayMax      atomic.Int64
+}
+
+// recordDelay 记录一次通知落后最先到达端点的时间, 胜出时为0
+func (e *endpoint) recordDelay(delay time.Duration) {
+	e.delaySum.Add(int64(delay))
+	for {
+		current := e.delayMax.Load()
+		if int64(delay) <= current || e.delayMax.CompareAndSwap(current, int64(delay)) {
+			return
+		}
+	}
+}
+
+// EndpointStats 单个订阅端点的统计
+type EndpointStats struct {
+	Endpoint      string        `json:"endpoint"`
+	State         ConnState     `json:"state"`
+	Notifications uint64        `json:"notifications"` // 收到的有效通知数量
+	Wins          uint64        `json:"wins"`          // 最先到达的数量
+	WinRate       float64       `json:"win_rate"`      // 最先到达的数量占全部端点最先到达数量的比例
+	AvgDelay      time.Duration `json:"avg_delay"`     // 平均落后最先到达端点的时间, 胜出时记为0
+	MaxDelay      time.Duration `json:"max_delay"`
+	Reconnects    uint64        `json:"reconnects"`
+}
+
+// statePriority 聚合多个端点的状态时的优先级, 数值越大越优先
+func statePriority(state ConnState) int {
+	switch state {
+	case ConnStateConnected:
+		return 5
+	case ConnStateConnecting:
+		return 4
+	case ConnStateDisconnected:
+		return 3
+	case ConnStateIdle:
+		return 2
+	case ConnStateClosed:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,149 @@
+package bonk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// ProgramError 交易中某条指令执行失败的错误
+//
+// 失败的程序是 launchpad 时 Err 为 idl 中对应的错误, 可以直接使用 errors.Is 判断:
+//
+//	errors.Is(err, raydium_launchpad.ErrExceededSlippage)
+type ProgramError struct {
+	InstructionIndex int              // 失败的外层指令索引
+	ProgramId        solana.PublicKey // 最先失败的程序(可能是外层指令 CPI 调用的程序), 日志中找不到时为空
+	Code             int              // 自定义错误码, 非自定义错误时为 -1
+	Reason           string           // 非自定义错误的原因, 例如 InvalidAccountData
+	Err              error            // launchpad 程序的错误, 错误码未知或失败的不是 launchpad 程序时为空
+}
+
+func (e *ProgramError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("指令 %d 执行失败: %s", e.InstructionIndex, e.Err)
+	case e.Code >= 0:
+		return fmt.Sprintf("指令 %d 执行失败: custom program error: 0x%x", e.InstructionIndex, e.Code)
+	default:
+		return fmt.Sprintf("指令 %d 执行失败: %s", e.InstructionIndex, e.Reason)
+	}
+}
+
+func (e *ProgramError) Unwrap() error {
+	return e.Err
+}
+
+// ParseTransactionError 将交易失败的原因转换为 Go 错误
+//
+// txErr 可以是 rpc.TransactionMeta.Err、rpc.SimulateTransactionResult.Err、ws.LogResult 中的 Err,
+// 也可以是发送交易时预检失败返回的 error. txErr 为空时返回 nil
+//
+// 自定义错误码只在程序内唯一, 需要根据日志确定失败的程序: logs 为交易对应的
+// rpc.TransactionMeta.LogMessages、rpc.SimulateTransactionResult.Logs 或 ws.LogResult 中的 Logs,
+// 预检失败的 error 自带日志. 没有日志时无法确定失败的程序, 不会关联 launchpad 的错误
+//
+// 能识别 InstructionError 时返回 *ProgramError, 否则原样包装返回
+func ParseTransactionError(txErr any, logs ...string) error {
+	if txErr == nil {
+		return nil
+	}
+	if err, ok := txErr.(error); ok {
+		var jErr *jsonrpc.RPCError
+		if errors.As(err, &jErr) {
+			if data, ok := jErr.Data.(map[string]any); ok {
+				if items, ok := data["logs"].([]any); ok && len(logs) == 0 {
+					for _, item := range items {
+						if line, ok := item.(string); ok {
+							logs = append(logs, line)
+						}
+					}
+				}
+				if programErr, ok := parseInstructionError(data["err"], logs); ok {
+					return programErr
+				}
+			}
+		}
+		return err
+	}
+	if programErr, ok := parseInstructionError(txErr, logs); ok {
+		return programErr
+	}
+	return fmt.Errorf("交易失败: %v", txErr)
+}
+
+// parseInstructionError 解析 {"InstructionError": [index, {"Custom": code}]} 结构
+func parseInstructionError(txErr any, logs []string) (*ProgramError, bool) {
+	root, ok := txErr.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	items, ok := root["InstructionError"].([]any)
+	if !ok || len(items) != 2 {
+		return nil, false
+	}
+	index, ok := toInt(items[0])
+	if !ok {
+		return nil, false
+	}
+
+	result := &ProgramError{InstructionIndex: index, Code: -1, ProgramId: failedProgram(logs)}
+	switch v := items[1].(type) {
+	case string:
+		result.Reason = v
+	case map[string]any:
+		if code, ok := toInt(v["Custom"]); ok {
+			result.Code = code
+			if result.ProgramId.Equals(raydium_launchpad.ProgramID) {
+				if customErr, ok := raydium_launchpad.Errors[code]; ok {
+					result.Err = customErr
+				}
+			}
+		} else {
+			result.Reason = fmt.Sprint(v)
+		}
+	default:
+		result.Reason = fmt.Sprint(v)
+	}
+	return result, true
+}
+
+// failedProgram 从日志中找出最先失败的程序
+//
+// CPI 调用失败时被调用的程序先输出 "Program <id> failed: ...", 外层程序随后输出同样的错误
+func failedProgram(logs []string) solana.PublicKey {
+	for _, line := range logs {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "Program" || fields[2] != "failed:" {
+			continue
+		}
+		if programId, err := solana.PublicKeyFromBase58(fields[1]); err == nil {
+			return programId
+		}
+	}
+	return solana.PublicKey{}
+}
+
+func toInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err == nil
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
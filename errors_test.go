@@ -0,0 +1,114 @@
+package bonk
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// decodeTxErr 与 rpc 客户端一样解码交易错误, 数字为 json.Number
+func decodeTxErr(t *testing.T, data string) any {
+	t.Helper()
+	var v any
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseTransactionError(t *testing.T) {
+	launchpad := raydium_launchpad.ProgramID.String()
+	router := solana.NewWallet().PublicKey()
+	slippage := `{"InstructionError":[2,{"Custom":6004}]}`
+	launchpadLogs := []string{
+		"Program " + launchpad + " invoke [1]",
+		"Program log: Instruction: BuyExactIn",
+		"Program " + launchpad + " consumed 30000 of 200000 compute units",
+		"Program " + launchpad + " failed: custom program error: 0x1774",
+	}
+
+	tests := []struct {
+		name    string
+		txErr   string
+		logs    []string
+		want    ProgramError
+		wantErr error
+	}{
+		{"launchpad", slippage, launchpadLogs,
+			ProgramError{InstructionIndex: 2, ProgramId: raydium_launchpad.ProgramID, Code: 6004}, raydium_launchpad.ErrExceededSlippage},
+		// 外层程序 CPI 调用 launchpad 失败
+		{"cpi", slippage, []string{
+			"Program " + router.String() + " invoke [1]",
+			"Program " + launchpad + " invoke [2]",
+			"Program " + launchpad + " failed: custom program error: 0x1774",
+			"Program " + router.String() + " failed: custom program error: 0x1774",
+		}, ProgramError{InstructionIndex: 2, ProgramId: raydium_launchpad.ProgramID, Code: 6004}, raydium_launchpad.ErrExceededSlippage},
+		// 其他程序的错误码与 launchpad 相同
+		{"other_program", slippage, []string{
+			"Program " + router.String() + " invoke [1]",
+			"Program " + router.String() + " failed: custom program error: 0x1774",
+		}, ProgramError{InstructionIndex: 2, ProgramId: router, Code: 6004}, nil},
+		{"no_logs", slippage, nil, ProgramError{InstructionIndex: 2, Code: 6004}, nil},
+		{"unknown_code", `{"InstructionError":[0,{"Custom":1}]}`, launchpadLogs,
+			ProgramError{InstructionIndex: 0, ProgramId: raydium_launchpad.ProgramID, Code: 1}, nil},
+		{"reason", `{"InstructionError":[1,"InvalidAccountData"]}`, nil,
+			ProgramError{InstructionIndex: 1, Code: -1, Reason: "InvalidAccountData"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseTransactionError(decodeTxErr(t, tt.txErr), tt.logs...)
+			var programErr *ProgramError
+			if !errors.As(err, &programErr) {
+				t.Fatalf("ParseTransactionError() = %v, want *ProgramError", err)
+			}
+			if programErr.Err != tt.wantErr {
+				t.Errorf("Err = %v, want %v", programErr.Err, tt.wantErr)
+			}
+			programErr.Err = nil
+			if *programErr != tt.want {
+				t.Errorf("got %+v, want %+v", *programErr, tt.want)
+			}
+		})
+	}
+
+	if err := ParseTransactionError(nil); err != nil {
+		t.Errorf("ParseTransactionError(nil) = %v, want nil", err)
+	}
+	if err := ParseTransactionError(decodeTxErr(t, `"AccountNotFound"`)); err == nil || errors.As(err, new(*ProgramError)) {
+		t.Errorf("ParseTransactionError(AccountNotFound) = %v", err)
+	}
+}
+
+func TestParseTransactionErrorPreflight(t *testing.T) {
+	// 预检失败时 data 中带有交易错误和日志
+	var data map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"err": {"InstructionError": [3, {"Custom": 6015}]},
+		"logs": ["Program LanMV9sAd7wArD4vJFi2qDdfnVhFxYSUg6eADduJ3uj failed: custom program error: 0x177f"]
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	rpcErr := &jsonrpc.RPCError{Code: -32002, Message: "Transaction simulation failed", Data: data}
+
+	err := ParseTransactionError(rpcErr)
+	if !errors.Is(err, raydium_launchpad.ErrPoolNotMigrated) {
+		t.Errorf("ParseTransactionError() = %v, want ErrPoolNotMigrated", err)
+	}
+	var programErr *ProgramError
+	if !errors.As(err, &programErr) || programErr.InstructionIndex != 3 {
+		t.Errorf("ParseTransactionError() = %v, want instruction 3", err)
+	}
+
+	other := errors.New("connection refused")
+	if err := ParseTransactionError(other); err != other {
+		t.Errorf("ParseTransactionError() = %v, want original error", err)
+	}
+}
@@ -0,0 +1,248 @@
+package bonk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/go-log"
+)
+
+// EventIxTag anchor emit_cpi! 自调用指令数据的前缀
+var EventIxTag = [8]byte{0xe4, 0x45, 0xa5, 0x2e, 0x51, 0xcb, 0x9a, 0x1d}
+
+// EventSource 事件的来源
+type EventSource string
+
+const (
+	EventSourceLog EventSource = "log" // Program data: 日志
+	EventSourceCpi EventSource = "cpi" // 发往 event authority 的自调用指令
+)
+
+// LaunchpadEvent 从交易中提取出的launchpad事件
+type LaunchpadEvent struct {
+	Signature        string      `json:"signature"`
+	Slot             uint64      `json:"slot"`
+	InstructionIndex int         `json:"instruction_index"` // 外层指令索引
+	InnerIndex       int         `json:"inner_index"`       // inner instruction 索引, 日志事件为 -1
+	Source           EventSource `json:"source"`
+	Name             string      `json:"name"`
+	// *TradeEvent / *PoolCreateEvent / *CreateVestingEvent / *ClaimVestedEvent
+	Event    any    `json:"event"`
+	raw      []byte // 事件原始数据, 用于去重
+	position int    // 在外层指令的日志和调用中的位置, 用于排序
+}
+
+// eventName 获取事件的名称
+func eventName(event any) string {
+	switch event.(type) {
+	case *raydium_launchpad.TradeEvent:
+		return "TradeEvent"
+	case *raydium_launchpad.PoolCreateEvent:
+		return "PoolCreateEvent"
+	case *raydium_launchpad.CreateVestingEvent:
+		return "CreateVestingEvent"
+	case *raydium_launchpad.ClaimVestedEvent:
+		return "ClaimVestedEvent"
+	default:
+		return "Unknown"
+	}
+}
+
+// parseProgramInvoke 解析 "Program <id> invoke [n]" 日志
+func parseProgramInvoke(logMsg string) (solana.PublicKey, bool) {
+	if !strings.HasPrefix(logMsg, "Program ") || !strings.Contains(logMsg, " invoke [") {
+		return solana.PublicKey{}, false
+	}
+	fields := strings.Fields(logMsg)
+	if len(fields) < 3 {
+		return solana.PublicKey{}, false
+	}
+	programID, err := solana.PublicKeyFromBase58(fields[1])
+	if err != nil {
+		return solana.PublicKey{}, false
+	}
+	return programID, true
+}
+
+// isProgramExit 判断是否是 "Program <id> success/failed" 日志
+func isProgramExit(logMsg string) bool {
+	if !strings.HasPrefix(logMsg, "Program ") {
+		return false
+	}
+	fields := strings.Fields(logMsg)
+	return len(fields) >= 3 && (fields[2] == "success" || fields[2] == "failed:")
+}
+
+// ExtractLogEvents 从交易日志的 "Program data:" 中提取launchpad事件
+//
+// 只提取由launchpad程序自身输出的日志, 并根据 invoke [1] 计算所属的外层指令
+func ExtractLogEvents(signature string, slot uint64, logs []string) []*LaunchpadEvent {
+	var (
+		result           []*LaunchpadEvent
+		stack            []solana.PublicKey
+		instructionIndex = -1
+		invoked          = 0 // 当前外层指令下已经调用的 inner instruction 数量
+	)
+	for _, logMsg := range logs {
+		if programID, ok := parseProgramInvoke(logMsg); ok {
+			if depth, _ := parseInvokeDepth(logMsg); depth == 1 {
+				instructionIndex++
+				invoked = 0
+				stack = stack[:0]
+			} else {
+				invoked++
+			}
+			stack = append(stack, programID)
+			continue
+		}
+		if isProgramExit(logMsg) {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if !strings.HasPrefix(logMsg, "Program data: ") {
+			continue
+		}
+		if len(stack) == 0 || !stack[len(stack)-1].Equals(raydium_launchpad.ProgramID) {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(logMsg, "Program data: "))
+		if len(fields) == 0 {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			continue
+		}
+		event, err := raydium_launchpad.ParseAnyEvent(data)
+		if err != nil {
+			continue
+		}
+		result = append(result, &LaunchpadEvent{
+			Signature:        signature,
+			Slot:             slot,
+			InstructionIndex: instructionIndex,
+			InnerIndex:       -1,
+			Source:           EventSourceLog,
+			Name:             eventName(event),
+			Event:            event,
+			raw:              data,
+			position:         2 * invoked, // 第 invoked 条 inner instruction 之后
+		})
+	}
+	return result
+}
+
+// ExtractCpiEvents 从发往 event authority 的自调用 inner instruction 中提取launchpad事件
+func ExtractCpiEvents(signature string, slot uint64, transaction *solana.Transaction, meta *rpc.TransactionMeta) []*LaunchpadEvent {
+	if transaction == nil || meta == nil {
+		return nil
+	}
+	eventAuthority, _, err := FindEventAuthorityPDA()
+	if err != nil {
+		return nil
+	}
+	var result []*LaunchpadEvent
+	for _, inner := range meta.InnerInstructions {
+		for j, instruction := range inner.Instructions {
+			programID, err := transaction.Message.Program(instruction.ProgramIDIndex)
+			if err != nil || !programID.Equals(raydium_launchpad.ProgramID) {
+				continue
+			}
+			if len(instruction.Data) < 16 || !bytes.Equal(instruction.Data[:8], EventIxTag[:]) {
+				continue
+			}
+			// emit_cpi! 的唯一账户是签名的 event authority, 其他账户调用的不是事件
+			accounts, err := instruction.ResolveInstructionAccounts(&transaction.Message)
+			if err != nil || len(accounts) == 0 || !accounts[0].PublicKey.Equals(eventAuthority) {
+				continue
+			}
+			data := []byte(instruction.Data[8:])
+			event, err := raydium_launchpad.ParseAnyEvent(data)
+			if err != nil {
+				continue
+			}
+			result = append(result, &LaunchpadEvent{
+				Signature:        signature,
+				Slot:             slot,
+				InstructionIndex: int(inner.Index),
+				InnerIndex:       j,
+				Source:           EventSourceCpi,
+				Name:             eventName(event),
+				Event:            event,
+				raw:              data,
+				position:         2*j + 1, // 第 j+1 条 inner instruction
+			})
+		}
+	}
+	return result
+}
+
+// ExtractEvents 从完整交易中提取全部launchpad事件, 按程序执行的顺序返回
+//
+// 同一条外层指令下, 日志与自调用中内容完全相同的事件只保留自调用中的一份;
+// v0交易会使用meta中的 LoadedAddresses 解析地址查找表
+func ExtractEvents(signature string, transaction *rpc.GetTransactionResult) ([]*LaunchpadEvent, error) {
+	if transaction == nil || transaction.Meta == nil {
+		return nil, errors.New("交易信息为空")
+	}
+	transactionInfo, err := transaction.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("解析交易失败: %w", err)
+	}
+	if err := ResolveLoadedAddresses(transactionInfo, transaction.Meta); err != nil {
+		return nil, err
+	}
+	return extractEvents(signature, transaction, transactionInfo), nil
+}
+
+// extractEvents transactionInfo 为已经解析过地址查找表的交易
+func extractEvents(signature string, transaction *rpc.GetTransactionResult, transactionInfo *solana.Transaction) []*LaunchpadEvent {
+	cpiEvents := ExtractCpiEvents(signature, transaction.Slot, transactionInfo, transaction.Meta)
+	logEvents := ExtractLogEvents(signature, transaction.Slot, transaction.Meta.LogMessages)
+
+	result := make([]*LaunchpadEvent, 0, len(cpiEvents)+len(logEvents))
+	result = append(result, cpiEvents...)
+	for _, event := range logEvents {
+		duplicate := false
+		for _, cpiEvent := range cpiEvents {
+			if cpiEvent.InstructionIndex == event.InstructionIndex && bytes.Equal(cpiEvent.raw, event.raw) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, event)
+		}
+	}
+
+	// 同一条外层指令下按日志中 invoke 的顺序排列: 日志事件排在它之前调用的 inner instruction 之后
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].InstructionIndex != result[j].InstructionIndex {
+			return result[i].InstructionIndex < result[j].InstructionIndex
+		}
+		return result[i].position < result[j].position
+	})
+	return result
+}
+
+// ProcessTransactionEvents 获取交易并提取其中的全部launchpad事件
+func (p *PoolMonit) ProcessTransactionEvents(signature solana.Signature) ([]*LaunchpadEvent, error) {
+	transaction, transactionInfo, err := p.getTransaction(p.ctx, signature)
+	if err != nil {
+		return nil, err
+	}
+	events := extractEvents(signature.String(), transaction, transactionInfo)
+	log.Info(fmt.Sprintf("交易 %s 提取到 %d 个事件", signature, len(events)))
+	return events, nil
+}
@@ -0,0 +1,178 @@
+package bonk
+
+import (
+	"encoding/base64"
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// tradeEventData 带 discriminator 的 TradeEvent 数据, amountIn 用于区分事件
+func tradeEventData(t *testing.T, amountIn uint64) []byte {
+	t.Helper()
+	body, err := raydium_launchpad.TradeEvent{AmountIn: amountIn}.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(raydium_launchpad.Event_TradeEvent[:], body...)
+}
+
+// eventTransaction 构建两条外层指令调用launchpad的交易, 日志和 inner instruction 与节点返回的一致:
+//
+//	指令0: 日志事件1 -> token CPI -> 自调用事件2 -> 伪造 authority 的自调用 -> 日志事件3 -> 日志事件2(与自调用重复)
+//	指令1: 自调用事件4
+func eventTransaction(t *testing.T) (*solana.Transaction, *rpc.TransactionMeta) {
+	t.Helper()
+	eventAuthority, _, err := FindEventAuthorityPDA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payer := solana.NewWallet().PublicKey()
+	forged := solana.NewWallet().PublicKey()
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(payer).WRITE().SIGNER(),
+		solana.Meta(eventAuthority),
+		solana.Meta(forged),
+		solana.Meta(solana.TokenProgramID),
+	}
+	instructions := []solana.Instruction{
+		solana.NewInstruction(raydium_launchpad.ProgramID, accounts, raydium_launchpad.Instruction_BuyExactIn[:]),
+		solana.NewInstruction(raydium_launchpad.ProgramID, accounts, raydium_launchpad.Instruction_SellExactIn[:]),
+	}
+	transaction, err := solana.NewTransaction(instructions, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := func(key solana.PublicKey) uint16 {
+		for i, account := range transaction.Message.AccountKeys {
+			if account.Equals(key) {
+				return uint16(i)
+			}
+		}
+		t.Fatalf("account %s not in transaction", key)
+		return 0
+	}
+	eventCpi := func(authority solana.PublicKey, amountIn uint64) solana.CompiledInstruction {
+		return solana.CompiledInstruction{
+			ProgramIDIndex: index(raydium_launchpad.ProgramID),
+			Accounts:       []uint16{index(authority)},
+			Data:           append(EventIxTag[:], tradeEventData(t, amountIn)...),
+		}
+	}
+	programData := func(amountIn uint64) string {
+		return "Program data: " + base64.StdEncoding.EncodeToString(tradeEventData(t, amountIn))
+	}
+	launchpad := "Program " + raydium_launchpad.ProgramID.String()
+	token := "Program " + solana.TokenProgramID.String()
+
+	meta := &rpc.TransactionMeta{
+		InnerInstructions: []rpc.InnerInstruction{
+			{Index: 0, Instructions: []solana.CompiledInstruction{
+				{ProgramIDIndex: index(solana.TokenProgramID), Accounts: []uint16{index(payer)}, Data: []byte{3}},
+				eventCpi(eventAuthority, 2),
+				eventCpi(forged, 5),
+			}},
+			{Index: 1, Instructions: []solana.CompiledInstruction{
+				eventCpi(eventAuthority, 4),
+			}},
+		},
+		LogMessages: []string{
+			launchpad + " invoke [1]",
+			"Program log: Instruction: BuyExactIn",
+			programData(1),
+			token + " invoke [2]",
+			programData(6), // 其他程序输出的数据不是launchpad事件
+			token + " success",
+			launchpad + " invoke [2]",
+			launchpad + " success",
+			launchpad + " invoke [2]",
+			launchpad + " success",
+			programData(3),
+			programData(2),
+			launchpad + " success",
+			launchpad + " invoke [1]",
+			"Program log: Instruction: SellExactIn",
+			launchpad + " invoke [2]",
+			launchpad + " success",
+			launchpad + " success",
+		},
+	}
+	return transaction, meta
+}
+
+// eventKey 事件的来源、外层指令和 AmountIn
+type eventKey struct {
+	source      EventSource
+	instruction int
+	amountIn    uint64
+}
+
+func eventKeys(t *testing.T, events []*LaunchpadEvent) []eventKey {
+	t.Helper()
+	keys := make([]eventKey, len(events))
+	for i, event := range events {
+		trade, ok := event.Event.(*raydium_launchpad.TradeEvent)
+		if !ok || event.Name != "TradeEvent" {
+			t.Fatalf("event %d = %s %T", i, event.Name, event.Event)
+		}
+		keys[i] = eventKey{event.Source, event.InstructionIndex, trade.AmountIn}
+	}
+	return keys
+}
+
+func checkEvents(t *testing.T, name string, events []*LaunchpadEvent, want []eventKey) {
+	t.Helper()
+	got := eventKeys(t, events)
+	if len(got) != len(want) {
+		t.Fatalf("%s = %v, want %v", name, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s[%d] = %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtractLogEvents(t *testing.T) {
+	_, meta := eventTransaction(t)
+	events := ExtractLogEvents("sig", 7, meta.LogMessages)
+	checkEvents(t, "ExtractLogEvents()", events, []eventKey{
+		{EventSourceLog, 0, 1},
+		{EventSourceLog, 0, 3},
+		{EventSourceLog, 0, 2},
+	})
+	for _, event := range events {
+		if event.Signature != "sig" || event.Slot != 7 || event.InnerIndex != -1 {
+			t.Errorf("event = %+v", event)
+		}
+	}
+}
+
+func TestExtractCpiEvents(t *testing.T) {
+	transaction, meta := eventTransaction(t)
+	events := ExtractCpiEvents("sig", 7, transaction, meta)
+	// 账户不是 event authority 的自调用被忽略
+	checkEvents(t, "ExtractCpiEvents()", events, []eventKey{
+		{EventSourceCpi, 0, 2},
+		{EventSourceCpi, 1, 4},
+	})
+	if events[0].InnerIndex != 1 || events[1].InnerIndex != 0 {
+		t.Errorf("InnerIndex = %d, %d, want 1, 0", events[0].InnerIndex, events[1].InnerIndex)
+	}
+}
+
+func TestExtractEvents(t *testing.T) {
+	transaction, meta := eventTransaction(t)
+	events := extractEvents("sig", &rpc.GetTransactionResult{Slot: 7, Meta: meta}, transaction)
+	// 与自调用重复的日志事件只保留自调用, 日志事件3在自调用事件2之后输出
+	checkEvents(t, "extractEvents()", events, []eventKey{
+		{EventSourceLog, 0, 1},
+		{EventSourceCpi, 0, 2},
+		{EventSourceLog, 0, 3},
+		{EventSourceCpi, 1, 4},
+	})
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"time"
 
 	"github.com/go-enols/go-log"
 
@@ -35,16 +38,41 @@ func main() {
 
 	monit := bonk.NewClient(ctx, opt)
 
-	monit.UseLog(poolMonitClient.ProcessTransactionLogs) // 添加一个处理交易日志的中间件
+	// 添加一个处理交易日志的中间件, 只处理包含 Initialize 指令的日志
+	monit.Use(poolMonitClient, bonk.HandlerOptions{
+		Name:    "initialize",
+		Timeout: 30 * time.Second,
+		Filters: []bonk.LogFilter{bonk.ContainsInstruction("Initialize")},
+	})
+	monit.OnHandlerError(func(err *bonk.HandlerError) {
+		log.Error(err)
+	})
+
+	monit.OnStateChange(func(event bonk.ConnEvent) {
+		log.Printf("连接状态 %s -> %s", event.From, event.State)
+	})
 
-	go monit.Start(ctx, ProgramID, rpc.CommitmentConfirmed)
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case data := <-poolMonitClient.Pip:
-			log.Printf("交易 %s 处理成功", data.Signature)
-			log.Info(data)
+	// 收到退出信号后停止订阅, 等待已经收到的交易处理完成
+	stop, stopCancel := signal.NotifyContext(ctx, os.Interrupt)
+	defer stopCancel()
+	go func() {
+		// 连续重连失败次数超出上限时退出
+		if _, err := monit.Start(stop, ProgramID, rpc.CommitmentConfirmed); err != nil {
+			log.Error(err)
 		}
+		closeCtx, closeCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer closeCancel()
+		if err := monit.Close(closeCtx); err != nil {
+			log.Error(err)
+		}
+		if err := poolMonitClient.Close(closeCtx); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	// Close 之后 Pip 会被关闭, range 自动结束
+	for data := range poolMonitClient.Pip {
+		log.Printf("交易 %s 处理成功", data.Signature)
+		log.Info(data)
 	}
 }
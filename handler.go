@@ -0,0 +1,300 @@
+package bonk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/go-enols/gosolana/ws"
+)
+
+var (
+	ErrHandlerPanic   = errors.New("中间件 panic")
+	ErrHandlerTimeout = errors.New("中间件处理超时")
+)
+
+// LogHandler 日志中间件, ctx 在超时或 Client 停止时结束
+type LogHandler interface {
+	HandleLog(ctx context.Context, log *ws.LogResult) error
+}
+
+// LogHandlerFunc 函数形式的日志中间件
+type LogHandlerFunc func(ctx context.Context, log *ws.LogResult) error
+
+func (f LogHandlerFunc) HandleLog(ctx context.Context, log *ws.LogResult) error {
+	return f(ctx, log)
+}
+
+// HandleLog 兼容旧的中间件, 不返回错误也不感知超时
+func (f LogApusic) HandleLog(_ context.Context, log *ws.LogResult) error {
+	f(log)
+	return nil
+}
+
+// LogFilter 日志过滤器, 返回 false 时不交给中间件处理
+type LogFilter func(*ws.LogResult) bool
+
+// ContainsInstruction 只处理包含指定指令的日志, 例如 "Initialize"、"BuyExactIn"
+//
+// 按 Anchor 输出的 "Program log: Instruction: <name>" 完整匹配, Initialize 不会匹配 InitializeV2
+func ContainsInstruction(names ...string) LogFilter {
+	lines := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		lines["Program log: Instruction: "+name] = struct{}{}
+	}
+	return func(msg *ws.LogResult) bool {
+		for _, line := range msg.Value.Logs {
+			if _, ok := lines[strings.TrimSpace(line)]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ContainsLog 只处理任意一行包含 substr 的日志
+func ContainsLog(substr string) LogFilter {
+	return func(msg *ws.LogResult) bool {
+		for _, line := range msg.Value.Logs {
+			if strings.Contains(line, substr) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HandlerOptions 中间件选项
+type HandlerOptions struct {
+	Name    string        // 名称, 用于错误回调和日志, 默认 handler-<序号>
+	Timeout time.Duration // 单条日志的处理超时, 0 表示不限制; 中间件需要响应 ctx 才能真正中断
+	Ordered bool          // 按日志到达的顺序逐条处理, 同一个中间件不会并发执行
+	Filters []LogFilter   // 全部过滤器都通过才会处理
+}
+
+// HandlerError 中间件返回的错误、panic 或超时
+type HandlerError struct {
+	Handler   string
+	Signature solana.Signature
+	Err       error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("中间件 %s 处理交易 %s 失败: %v", e.Handler, e.Signature, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerErrorHandler 中间件错误回调, 在中间件协程中同步调用, 不要阻塞
+type HandlerErrorHandler func(*HandlerError)
+
+// logHandler 已注册的中间件
+type logHandler struct {
+	handler LogHandler
+	options HandlerOptions
+	queue   chan *ws.LogResult // Ordered 时的待处理日志
+	done    chan struct{}
+	once    sync.Once
+}
+
+// match 是否需要处理该日志
+func (h *logHandler) match(msg *ws.LogResult) bool {
+	for _, filter := range h.options.Filters {
+		if filter != nil && !filter(msg) {
+			return false
+		}
+	}
+	return true
+}
+
+// call 调用中间件, panic 转换为错误
+func (h *logHandler) call(ctx context.Context, msg *ws.LogResult) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v\n%s", ErrHandlerPanic, r, debug.Stack())
+		}
+	}()
+	return h.handler.HandleLog(ctx, msg)
+}
+
+// LogHandle UseLog 返回的句柄, 用于在运行时移除中间件
+type LogHandle struct {
+	c *Client
+	h *logHandler
+}
+
+// Name 中间件名称
+func (h *LogHandle) Name() string {
+	return h.h.options.Name
+}
+
+// Remove 移除中间件, 不再接收新的日志, 正在处理的日志不受影响, 可以重复调用
+func (h *LogHandle) Remove() {
+	h.c.lock.Lock()
+	for i, handler := range h.c.handlers {
+		if handler == h.h {
+			h.c.handlers = append(h.c.handlers[:i:i], h.c.handlers[i+1:]...)
+			break
+		}
+	}
+	h.c.lock.Unlock()
+	h.h.once.Do(func() {
+		close(h.h.done)
+	})
+}
+
+// 添加一个日志中间件，在获取到日志后，异步处理
+func (c *Client) UseLog(apusic LogApusic, options ...HandlerOptions) *LogHandle {
+	c.lock.Lock()
+	c.LogApusic = append(c.LogApusic, apusic)
+	c.lock.Unlock()
+	return c.Use(apusic, options...)
+}
+
+// Use 添加一个日志中间件, 返回的句柄可以在运行时移除该中间件
+func (c *Client) Use(handler LogHandler, options ...HandlerOptions) *LogHandle {
+	var opts HandlerOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	// 加锁确保写入安全
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handlerSeq++
+	if opts.Name == "" {
+		opts.Name = fmt.Sprintf("handler-%d", c.handlerSeq)
+	}
+	h := &logHandler{
+		handler: handler,
+		options: opts,
+		done:    make(chan struct{}),
+	}
+	if c.closed {
+		// 关闭后添加的中间件不会收到日志
+		return &LogHandle{c: c, h: h}
+	}
+	if opts.Ordered {
+		h.queue = make(chan *ws.LogResult, cap(c.LogQuque))
+		c.dispatch.wg.Add(1)
+		go c.runOrdered(c.handlerCtx, h)
+	}
+	c.handlers = append(c.handlers, h)
+	return &LogHandle{c: c, h: h}
+}
+
+// OnHandlerError 添加一个中间件错误回调, 没有回调时错误只输出到日志
+func (c *Client) OnHandlerError(handler HandlerErrorHandler) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.errorHandlers = append(c.errorHandlers, handler)
+}
+
+// dispatchLog 将一条日志交给全部匹配的中间件, ctx 结束时返回 false
+func (c *Client) dispatchLog(ctx context.Context, msg *ws.LogResult) bool {
+	c.lock.RLock()
+	handlers := append([]*logHandler(nil), c.handlers...)
+	c.lock.RUnlock()
+
+	for _, h := range handlers {
+		if !h.match(msg) {
+			continue
+		}
+		if h.queue != nil {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-h.done:
+			case h.queue <- msg:
+			}
+			continue
+		}
+		if !c.acquireWorker(ctx) {
+			return false
+		}
+		go func(h *logHandler) {
+			defer c.releaseWorker()
+			c.runHandler(ctx, h, msg)
+		}(h) // 异步处理启动所有
+	}
+	return true
+}
+
+// runOrdered 按顺序逐条处理 Ordered 中间件的日志, 直到中间件被移除或队列被关闭
+func (c *Client) runOrdered(ctx context.Context, h *logHandler) {
+	defer c.dispatch.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.done:
+			return
+		case msg, ok := <-h.queue:
+			if !ok {
+				return
+			}
+			if !c.acquireWorker(ctx) {
+				return
+			}
+			c.runHandler(ctx, h, msg)
+			c.releaseWorker()
+		}
+	}
+}
+
+// runHandler 在超时限制内调用中间件并上报错误
+func (c *Client) runHandler(ctx context.Context, h *logHandler, msg *ws.LogResult) {
+	if h.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.options.Timeout)
+		defer cancel()
+	}
+	err := h.call(ctx, msg)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		if err == nil {
+			err = fmt.Errorf("%w(%s)", ErrHandlerTimeout, h.options.Timeout)
+		} else if !errors.Is(err, ErrHandlerTimeout) {
+			err = fmt.Errorf("%w(%s): %w", ErrHandlerTimeout, h.options.Timeout, err)
+		}
+	}
+	if err != nil {
+		c.reportHandlerError(&HandlerError{
+			Handler:   h.options.Name,
+			Signature: msg.Value.Signature,
+			Err:       err,
+		})
+	}
+}
+
+// closeOrderedQueues 日志队列分发完成后关闭 Ordered 中间件的队列, 剩余的日志处理完后协程退出
+func (c *Client) closeOrderedQueues() {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, h := range c.handlers {
+		if h.queue != nil {
+			close(h.queue)
+		}
+	}
+}
+
+func (c *Client) reportHandlerError(err *HandlerError) {
+	c.lock.RLock()
+	handlers := append([]HandlerErrorHandler(nil), c.errorHandlers...)
+	c.lock.RUnlock()
+
+	if len(handlers) == 0 {
+		log.Println(err)
+		return
+	}
+	for _, handler := range handlers {
+		handler(err)
+	}
+}
@@ -0,0 +1,133 @@
+package raydium_launchpad
+
+import "fmt"
+
+// CustomError launchpad 程序的自定义错误, 与 IDL 中的 errors 保持一致
+//
+// anchor-go 生成的 errors.go 不包含错误定义, 这里手动维护, 重新生成代码时不会被覆盖.
+// 交易错误的解析见 bonk.ParseTransactionError
+type CustomError interface {
+	Code() int
+	Name() string
+	Error() string
+}
+
+type customErrorDef struct {
+	code int
+	name string
+	msg  string
+}
+
+func (e *customErrorDef) Code() int {
+	return e.code
+}
+
+func (e *customErrorDef) Name() string {
+	return e.name
+}
+
+func (e *customErrorDef) Error() string {
+	return fmt.Sprintf("%s(%d): %s", e.name, e.code, e.msg)
+}
+
+var (
+	ErrNotApproved = &customErrorDef{
+		code: 6000,
+		msg:  "Not approved",
+		name: "NotApproved",
+	}
+	ErrInvalidOwner = &customErrorDef{
+		code: 6001,
+		msg:  "Input account owner is not the program address",
+		name: "InvalidOwner",
+	}
+	ErrInvalidInput = &customErrorDef{
+		code: 6002,
+		msg:  "InvalidInput",
+		name: "InvalidInput",
+	}
+	ErrInputNotMatchCurveConfig = &customErrorDef{
+		code: 6003,
+		msg:  "The input params are not match with curve type in config",
+		name: "InputNotMatchCurveConfig",
+	}
+	ErrExceededSlippage = &customErrorDef{
+		code: 6004,
+		msg:  "Exceeds desired slippage limit",
+		name: "ExceededSlippage",
+	}
+	ErrPoolFunding = &customErrorDef{
+		code: 6005,
+		msg:  "Pool funding",
+		name: "PoolFunding",
+	}
+	ErrPoolMigrated = &customErrorDef{
+		code: 6006,
+		msg:  "Pool migrated",
+		name: "PoolMigrated",
+	}
+	ErrMigrateTypeNotMatch = &customErrorDef{
+		code: 6007,
+		msg:  "Migrate type not match",
+		name: "MigrateTypeNotMatch",
+	}
+	ErrMathOverflow = &customErrorDef{
+		code: 6008,
+		msg:  "Math overflow",
+		name: "MathOverflow",
+	}
+	ErrNoAssetsToCollect = &customErrorDef{
+		code: 6009,
+		msg:  "No assets to collect",
+		name: "NoAssetsToCollect",
+	}
+	ErrVestingRatioTooHigh = &customErrorDef{
+		code: 6010,
+		msg:  "Vesting ratio too high",
+		name: "VestingRatioTooHigh",
+	}
+	ErrVestingSettingEnded = &customErrorDef{
+		code: 6011,
+		msg:  "Vesting setting ended",
+		name: "VestingSettingEnded",
+	}
+	ErrVestingNotStarted = &customErrorDef{
+		code: 6012,
+		msg:  "Vesting not started",
+		name: "VestingNotStarted",
+	}
+	ErrNoVestingSchedule = &customErrorDef{
+		code: 6013,
+		msg:  "No vesting schedule",
+		name: "NoVestingSchedule",
+	}
+	ErrInvalidPlatformInfo = &customErrorDef{
+		code: 6014,
+		msg:  "The platform info input is invalid",
+		name: "InvalidPlatformInfo",
+	}
+	ErrPoolNotMigrated = &customErrorDef{
+		code: 6015,
+		msg:  "Pool not migrated",
+		name: "PoolNotMigrated",
+	}
+	// Errors 按错误码索引全部自定义错误
+	Errors = map[int]CustomError{
+		6000: ErrNotApproved,
+		6001: ErrInvalidOwner,
+		6002: ErrInvalidInput,
+		6003: ErrInputNotMatchCurveConfig,
+		6004: ErrExceededSlippage,
+		6005: ErrPoolFunding,
+		6006: ErrPoolMigrated,
+		6007: ErrMigrateTypeNotMatch,
+		6008: ErrMathOverflow,
+		6009: ErrNoAssetsToCollect,
+		6010: ErrVestingRatioTooHigh,
+		6011: ErrVestingSettingEnded,
+		6012: ErrVestingNotStarted,
+		6013: ErrNoVestingSchedule,
+		6014: ErrInvalidPlatformInfo,
+		6015: ErrPoolNotMigrated,
+	}
+)
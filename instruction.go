@@ -0,0 +1,798 @@
+package bonk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	binary "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/go-enols/go-log"
+)
+
+var (
+	ErrNotLaunchpadInstruction = errors.New("不是launchpad程序的指令")
+	ErrUnknownInstruction      = errors.New("未知的launchpad指令")
+)
+
+// LaunchpadInstruction 解析后的launchpad指令, 具体类型为 *BuyExactInInstruction 等
+type LaunchpadInstruction interface {
+	InstructionName() string
+}
+
+// TradeAccounts buy_exact_in/buy_exact_out/sell_exact_in/sell_exact_out 共用的账户
+type TradeAccounts struct {
+	Payer             solana.PublicKey `json:"payer"`
+	Authority         solana.PublicKey `json:"authority"`
+	GlobalConfig      solana.PublicKey `json:"global_config"`
+	PlatformConfig    solana.PublicKey `json:"platform_config"`
+	PoolState         solana.PublicKey `json:"pool_state"`
+	UserBaseToken     solana.PublicKey `json:"user_base_token"`
+	UserQuoteToken    solana.PublicKey `json:"user_quote_token"`
+	BaseVault         solana.PublicKey `json:"base_vault"`
+	QuoteVault        solana.PublicKey `json:"quote_vault"`
+	BaseTokenMint     solana.PublicKey `json:"base_token_mint"`
+	QuoteTokenMint    solana.PublicKey `json:"quote_token_mint"`
+	BaseTokenProgram  solana.PublicKey `json:"base_token_program"`
+	QuoteTokenProgram solana.PublicKey `json:"quote_token_program"`
+	EventAuthority    solana.PublicKey `json:"event_authority"`
+	Program           solana.PublicKey `json:"program"`
+}
+
+func (a *TradeAccounts) fill(keys []solana.PublicKey) {
+	a.Payer = keys[0]
+	a.Authority = keys[1]
+	a.GlobalConfig = keys[2]
+	a.PlatformConfig = keys[3]
+	a.PoolState = keys[4]
+	a.UserBaseToken = keys[5]
+	a.UserQuoteToken = keys[6]
+	a.BaseVault = keys[7]
+	a.QuoteVault = keys[8]
+	a.BaseTokenMint = keys[9]
+	a.QuoteTokenMint = keys[10]
+	a.BaseTokenProgram = keys[11]
+	a.QuoteTokenProgram = keys[12]
+	a.EventAuthority = keys[13]
+	a.Program = keys[14]
+}
+
+// BuyExactInInstruction 使用指定数量的quote购买base
+type BuyExactInInstruction struct {
+	AmountIn         uint64        `json:"amount_in"`
+	MinimumAmountOut uint64        `json:"minimum_amount_out"`
+	ShareFeeRate     uint64        `json:"share_fee_rate"`
+	Accounts         TradeAccounts `json:"accounts"`
+}
+
+func (*BuyExactInInstruction) InstructionName() string { return "buy_exact_in" }
+
+// BuyExactOutInstruction 使用quote购买指定数量的base
+type BuyExactOutInstruction struct {
+	AmountOut       uint64        `json:"amount_out"`
+	MaximumAmountIn uint64        `json:"maximum_amount_in"`
+	ShareFeeRate    uint64        `json:"share_fee_rate"`
+	Accounts        TradeAccounts `json:"accounts"`
+}
+
+func (*BuyExactOutInstruction) InstructionName() string { return "buy_exact_out" }
+
+// SellExactInInstruction 卖出指定数量的base换取quote
+type SellExactInInstruction struct {
+	AmountIn         uint64        `json:"amount_in"`
+	MinimumAmountOut uint64        `json:"minimum_amount_out"`
+	ShareFeeRate     uint64        `json:"share_fee_rate"`
+	Accounts         TradeAccounts `json:"accounts"`
+}
+
+func (*SellExactInInstruction) InstructionName() string { return "sell_exact_in" }
+
+// SellExactOutInstruction 卖出base换取指定数量的quote
+type SellExactOutInstruction struct {
+	AmountOut       uint64        `json:"amount_out"`
+	MaximumAmountIn uint64        `json:"maximum_amount_in"`
+	ShareFeeRate    uint64        `json:"share_fee_rate"`
+	Accounts        TradeAccounts `json:"accounts"`
+}
+
+func (*SellExactOutInstruction) InstructionName() string { return "sell_exact_out" }
+
+// ClaimPlatformFeeAccounts claim_platform_fee 的账户
+type ClaimPlatformFeeAccounts struct {
+	PlatformFeeWallet      solana.PublicKey `json:"platform_fee_wallet"`
+	Authority              solana.PublicKey `json:"authority"`
+	PoolState              solana.PublicKey `json:"pool_state"`
+	PlatformConfig         solana.PublicKey `json:"platform_config"`
+	QuoteVault             solana.PublicKey `json:"quote_vault"`
+	RecipientTokenAccount  solana.PublicKey `json:"recipient_token_account"`
+	QuoteMint              solana.PublicKey `json:"quote_mint"`
+	TokenProgram           solana.PublicKey `json:"token_program"`
+	SystemProgram          solana.PublicKey `json:"system_program"`
+	AssociatedTokenProgram solana.PublicKey `json:"associated_token_program"`
+}
+
+// ClaimPlatformFeeInstruction 平台方领取手续费
+type ClaimPlatformFeeInstruction struct {
+	Accounts ClaimPlatformFeeAccounts `json:"accounts"`
+}
+
+func (*ClaimPlatformFeeInstruction) InstructionName() string { return "claim_platform_fee" }
+
+// ClaimVestedTokenAccounts claim_vested_token 的账户
+type ClaimVestedTokenAccounts struct {
+	Beneficiary            solana.PublicKey `json:"beneficiary"`
+	Authority              solana.PublicKey `json:"authority"`
+	PoolState              solana.PublicKey `json:"pool_state"`
+	VestingRecord          solana.PublicKey `json:"vesting_record"`
+	BaseVault              solana.PublicKey `json:"base_vault"`
+	UserBaseToken          solana.PublicKey `json:"user_base_token"`
+	BaseTokenMint          solana.PublicKey `json:"base_token_mint"`
+	BaseTokenProgram       solana.PublicKey `json:"base_token_program"`
+	SystemProgram          solana.PublicKey `json:"system_program"`
+	AssociatedTokenProgram solana.PublicKey `json:"associated_token_program"`
+}
+
+// ClaimVestedTokenInstruction 领取已解锁的代币
+type ClaimVestedTokenInstruction struct {
+	Accounts ClaimVestedTokenAccounts `json:"accounts"`
+}
+
+func (*ClaimVestedTokenInstruction) InstructionName() string { return "claim_vested_token" }
+
+// CollectFeeAccounts collect_fee/collect_migrate_fee 共用的账户
+type CollectFeeAccounts struct {
+	Owner                 solana.PublicKey `json:"owner"`
+	Authority             solana.PublicKey `json:"authority"`
+	PoolState             solana.PublicKey `json:"pool_state"`
+	GlobalConfig          solana.PublicKey `json:"global_config"`
+	QuoteVault            solana.PublicKey `json:"quote_vault"`
+	QuoteMint             solana.PublicKey `json:"quote_mint"`
+	RecipientTokenAccount solana.PublicKey `json:"recipient_token_account"`
+	TokenProgram          solana.PublicKey `json:"token_program"`
+}
+
+func (a *CollectFeeAccounts) fill(keys []solana.PublicKey) {
+	a.Owner = keys[0]
+	a.Authority = keys[1]
+	a.PoolState = keys[2]
+	a.GlobalConfig = keys[3]
+	a.QuoteVault = keys[4]
+	a.QuoteMint = keys[5]
+	a.RecipientTokenAccount = keys[6]
+	a.TokenProgram = keys[7]
+}
+
+// CollectFeeInstruction 协议方领取交易手续费
+type CollectFeeInstruction struct {
+	Accounts CollectFeeAccounts `json:"accounts"`
+}
+
+func (*CollectFeeInstruction) InstructionName() string { return "collect_fee" }
+
+// CollectMigrateFeeInstruction 领取迁移手续费
+type CollectMigrateFeeInstruction struct {
+	Accounts CollectFeeAccounts `json:"accounts"`
+}
+
+func (*CollectMigrateFeeInstruction) InstructionName() string { return "collect_migrate_fee" }
+
+// CreateConfigAccounts create_config 的账户
+type CreateConfigAccounts struct {
+	Owner                 solana.PublicKey `json:"owner"`
+	GlobalConfig          solana.PublicKey `json:"global_config"`
+	QuoteTokenMint        solana.PublicKey `json:"quote_token_mint"`
+	ProtocolFeeOwner      solana.PublicKey `json:"protocol_fee_owner"`
+	MigrateFeeOwner       solana.PublicKey `json:"migrate_fee_owner"`
+	MigrateToAmmWallet    solana.PublicKey `json:"migrate_to_amm_wallet"`
+	MigrateToCpswapWallet solana.PublicKey `json:"migrate_to_cpswap_wallet"`
+	SystemProgram         solana.PublicKey `json:"system_program"`
+}
+
+// CreateConfigInstruction 创建全局配置
+type CreateConfigInstruction struct {
+	CurveType    uint8                `json:"curve_type"`
+	Index        uint16               `json:"index"`
+	MigrateFee   uint64               `json:"migrate_fee"`
+	TradeFeeRate uint64               `json:"trade_fee_rate"`
+	Accounts     CreateConfigAccounts `json:"accounts"`
+}
+
+func (*CreateConfigInstruction) InstructionName() string { return "create_config" }
+
+// CreatePlatformConfigAccounts create_platform_config 的账户
+type CreatePlatformConfigAccounts struct {
+	PlatformAdmin     solana.PublicKey `json:"platform_admin"`
+	PlatformFeeWallet solana.PublicKey `json:"platform_fee_wallet"`
+	PlatformNftWallet solana.PublicKey `json:"platform_nft_wallet"`
+	PlatformConfig    solana.PublicKey `json:"platform_config"`
+	SystemProgram     solana.PublicKey `json:"system_program"`
+}
+
+// CreatePlatformConfigInstruction 创建平台配置
+type CreatePlatformConfigInstruction struct {
+	PlatformParams raydium_launchpad.PlatformParams `json:"platform_params"`
+	Accounts       CreatePlatformConfigAccounts     `json:"accounts"`
+}
+
+func (*CreatePlatformConfigInstruction) InstructionName() string { return "create_platform_config" }
+
+// CreateVestingAccountAccounts create_vesting_account 的账户
+type CreateVestingAccountAccounts struct {
+	Creator       solana.PublicKey `json:"creator"`
+	Beneficiary   solana.PublicKey `json:"beneficiary"`
+	PoolState     solana.PublicKey `json:"pool_state"`
+	VestingRecord solana.PublicKey `json:"vesting_record"`
+	SystemProgram solana.PublicKey `json:"system_program"`
+}
+
+// CreateVestingAccountInstruction 创建锁仓账户
+type CreateVestingAccountInstruction struct {
+	ShareAmount uint64                       `json:"share_amount"`
+	Accounts    CreateVestingAccountAccounts `json:"accounts"`
+}
+
+func (*CreateVestingAccountInstruction) InstructionName() string { return "create_vesting_account" }
+
+// InitializeInstructionAccounts initialize 的账户
+type InitializeInstructionAccounts struct {
+	Payer             solana.PublicKey `json:"payer"`
+	Creator           solana.PublicKey `json:"creator"`
+	GlobalConfig      solana.PublicKey `json:"global_config"`
+	PlatformConfig    solana.PublicKey `json:"platform_config"`
+	Authority         solana.PublicKey `json:"authority"`
+	PoolState         solana.PublicKey `json:"pool_state"`
+	BaseMint          solana.PublicKey `json:"base_mint"`
+	QuoteMint         solana.PublicKey `json:"quote_mint"`
+	BaseVault         solana.PublicKey `json:"base_vault"`
+	QuoteVault        solana.PublicKey `json:"quote_vault"`
+	MetadataAccount   solana.PublicKey `json:"metadata_account"`
+	BaseTokenProgram  solana.PublicKey `json:"base_token_program"`
+	QuoteTokenProgram solana.PublicKey `json:"quote_token_program"`
+	MetadataProgram   solana.PublicKey `json:"metadata_program"`
+	SystemProgram     solana.PublicKey `json:"system_program"`
+	RentProgram       solana.PublicKey `json:"rent_program"`
+	EventAuthority    solana.PublicKey `json:"event_authority"`
+	Program           solana.PublicKey `json:"program"`
+}
+
+// InitializeInstruction 创建新的池子
+type InitializeInstruction struct {
+	MintParams    raydium_launchpad.MintParams    `json:"mint_params"`
+	CurveType     CurveType                       `json:"curve_type"`   // 曲线类型, 决定 CurveParams 的具体变体
+	CurveParams   raydium_launchpad.CurveParams   `json:"curve_params"` // *CurveParams_Constant / *CurveParams_Fixed / *CurveParams_Linear
+	VestingParams raydium_launchpad.VestingParams `json:"vesting_params"`
+	Accounts      InitializeInstructionAccounts   `json:"accounts"`
+}
+
+func (*InitializeInstruction) InstructionName() string { return "initialize" }
+
+// MigrateToAmmAccounts migrate_to_amm 的账户
+type MigrateToAmmAccounts struct {
+	Payer                   solana.PublicKey `json:"payer"`
+	BaseMint                solana.PublicKey `json:"base_mint"`
+	QuoteMint               solana.PublicKey `json:"quote_mint"`
+	OpenbookProgram         solana.PublicKey `json:"openbook_program"`
+	Market                  solana.PublicKey `json:"market"`
+	RequestQueue            solana.PublicKey `json:"request_queue"`
+	EventQueue              solana.PublicKey `json:"event_queue"`
+	Bids                    solana.PublicKey `json:"bids"`
+	Asks                    solana.PublicKey `json:"asks"`
+	MarketVaultSigner       solana.PublicKey `json:"market_vault_signer"`
+	MarketBaseVault         solana.PublicKey `json:"market_base_vault"`
+	MarketQuoteVault        solana.PublicKey `json:"market_quote_vault"`
+	AmmProgram              solana.PublicKey `json:"amm_program"`
+	AmmPool                 solana.PublicKey `json:"amm_pool"`
+	AmmAuthority            solana.PublicKey `json:"amm_authority"`
+	AmmOpenOrders           solana.PublicKey `json:"amm_open_orders"`
+	AmmLpMint               solana.PublicKey `json:"amm_lp_mint"`
+	AmmBaseVault            solana.PublicKey `json:"amm_base_vault"`
+	AmmQuoteVault           solana.PublicKey `json:"amm_quote_vault"`
+	AmmTargetOrders         solana.PublicKey `json:"amm_target_orders"`
+	AmmConfig               solana.PublicKey `json:"amm_config"`
+	AmmCreateFeeDestination solana.PublicKey `json:"amm_create_fee_destination"`
+	Authority               solana.PublicKey `json:"authority"`
+	PoolState               solana.PublicKey `json:"pool_state"`
+	GlobalConfig            solana.PublicKey `json:"global_config"`
+	BaseVault               solana.PublicKey `json:"base_vault"`
+	QuoteVault              solana.PublicKey `json:"quote_vault"`
+	PoolLpToken             solana.PublicKey `json:"pool_lp_token"`
+	SplTokenProgram         solana.PublicKey `json:"spl_token_program"`
+	AssociatedTokenProgram  solana.PublicKey `json:"associated_token_program"`
+	SystemProgram           solana.PublicKey `json:"system_program"`
+	RentProgram             solana.PublicKey `json:"rent_program"`
+}
+
+// MigrateToAmmInstruction 迁移到 Raydium AMM v4
+type MigrateToAmmInstruction struct {
+	BaseLotSize            uint64               `json:"base_lot_size"`
+	QuoteLotSize           uint64               `json:"quote_lot_size"`
+	MarketVaultSignerNonce uint8                `json:"market_vault_signer_nonce"`
+	Accounts               MigrateToAmmAccounts `json:"accounts"`
+}
+
+func (*MigrateToAmmInstruction) InstructionName() string { return "migrate_to_amm" }
+
+// MigrateToCpswapAccounts migrate_to_cpswap 的账户
+type MigrateToCpswapAccounts struct {
+	Payer                  solana.PublicKey `json:"payer"`
+	BaseMint               solana.PublicKey `json:"base_mint"`
+	QuoteMint              solana.PublicKey `json:"quote_mint"`
+	PlatformConfig         solana.PublicKey `json:"platform_config"`
+	CpswapProgram          solana.PublicKey `json:"cpswap_program"`
+	CpswapPool             solana.PublicKey `json:"cpswap_pool"`
+	CpswapAuthority        solana.PublicKey `json:"cpswap_authority"`
+	CpswapLpMint           solana.PublicKey `json:"cpswap_lp_mint"`
+	CpswapBaseVault        solana.PublicKey `json:"cpswap_base_vault"`
+	CpswapQuoteVault       solana.PublicKey `json:"cpswap_quote_vault"`
+	CpswapConfig           solana.PublicKey `json:"cpswap_config"`
+	CpswapCreatePoolFee    solana.PublicKey `json:"cpswap_create_pool_fee"`
+	CpswapObservation      solana.PublicKey `json:"cpswap_observation"`
+	LockProgram            solana.PublicKey `json:"lock_program"`
+	LockAuthority          solana.PublicKey `json:"lock_authority"`
+	LockLpVault            solana.PublicKey `json:"lock_lp_vault"`
+	Authority              solana.PublicKey `json:"authority"`
+	PoolState              solana.PublicKey `json:"pool_state"`
+	GlobalConfig           solana.PublicKey `json:"global_config"`
+	BaseVault              solana.PublicKey `json:"base_vault"`
+	QuoteVault             solana.PublicKey `json:"quote_vault"`
+	PoolLpToken            solana.PublicKey `json:"pool_lp_token"`
+	BaseTokenProgram       solana.PublicKey `json:"base_token_program"`
+	QuoteTokenProgram      solana.PublicKey `json:"quote_token_program"`
+	AssociatedTokenProgram solana.PublicKey `json:"associated_token_program"`
+	SystemProgram          solana.PublicKey `json:"system_program"`
+	RentProgram            solana.PublicKey `json:"rent_program"`
+	MetadataProgram        solana.PublicKey `json:"metadata_program"`
+}
+
+// MigrateToCpswapInstruction 迁移到 Raydium CPSWAP
+type MigrateToCpswapInstruction struct {
+	Accounts MigrateToCpswapAccounts `json:"accounts"`
+}
+
+func (*MigrateToCpswapInstruction) InstructionName() string { return "migrate_to_cpswap" }
+
+// UpdateConfigAccounts update_config 的账户
+type UpdateConfigAccounts struct {
+	Owner        solana.PublicKey `json:"owner"`
+	GlobalConfig solana.PublicKey `json:"global_config"`
+}
+
+// UpdateConfigInstruction 更新全局配置
+type UpdateConfigInstruction struct {
+	Param    uint8                `json:"param"`
+	Value    uint64               `json:"value"`
+	Accounts UpdateConfigAccounts `json:"accounts"`
+}
+
+func (*UpdateConfigInstruction) InstructionName() string { return "update_config" }
+
+// UpdatePlatformConfigAccounts update_platform_config 的账户
+type UpdatePlatformConfigAccounts struct {
+	PlatformAdmin  solana.PublicKey `json:"platform_admin"`
+	PlatformConfig solana.PublicKey `json:"platform_config"`
+}
+
+// UpdatePlatformConfigInstruction 更新平台配置
+type UpdatePlatformConfigInstruction struct {
+	Param    raydium_launchpad.PlatformConfigParam `json:"param"`
+	Accounts UpdatePlatformConfigAccounts          `json:"accounts"`
+}
+
+func (*UpdatePlatformConfigInstruction) InstructionName() string { return "update_platform_config" }
+
+// ParsedInstruction 交易中的一条launchpad指令
+type ParsedInstruction struct {
+	Signature    string               `json:"signature"`
+	Index        int                  `json:"index"`         // 外层指令索引
+	InnerIndex   int                  `json:"inner_index"`   // inner instruction 索引, 非CPI时为 -1
+	Depth        int                  `json:"depth"`         // 调用深度, 外层指令为 1, 0 表示未知
+	OuterProgram solana.PublicKey     `json:"outer_program"` // 外层指令调用的程序, 例如聚合器路由
+	Name         string               `json:"name"`
+	Instruction  LaunchpadInstruction `json:"instruction"`
+	TransferTime time.Time            `json:"transfer_time"`
+}
+
+// DecodeInstruction 解析交易中的一条launchpad指令, 返回对应的具体指令类型
+func DecodeInstruction(instruction solana.CompiledInstruction, transaction *solana.Transaction) (LaunchpadInstruction, error) {
+	programID, err := transaction.Message.Program(instruction.ProgramIDIndex)
+	if err != nil {
+		return nil, fmt.Errorf("解析程序ID失败: %w", err)
+	}
+	if !programID.Equals(raydium_launchpad.ProgramID) {
+		return nil, ErrNotLaunchpadInstruction
+	}
+
+	accountMetas, err := instruction.ResolveInstructionAccounts(&transaction.Message)
+	if err != nil {
+		return nil, fmt.Errorf("解析指令账户失败: %w", err)
+	}
+	keys := make([]solana.PublicKey, len(accountMetas))
+	for i, meta := range accountMetas {
+		keys[i] = meta.PublicKey
+	}
+
+	return decodeInstructionData(instruction.Data, keys)
+}
+
+// decodeInstructionData 根据discriminator解析指令参数以及账户
+func decodeInstructionData(data []byte, keys []solana.PublicKey) (LaunchpadInstruction, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("指令数据长度不足: %d", len(data))
+	}
+	var discriminator [8]byte
+	copy(discriminator[:], data[:8])
+	decoder := binary.NewBorshDecoder(data[8:])
+
+	switch discriminator {
+	case raydium_launchpad.Instruction_BuyExactIn:
+		ix := new(BuyExactInInstruction)
+		if err := decodeFields(decoder, &ix.AmountIn, &ix.MinimumAmountOut, &ix.ShareFeeRate); err != nil {
+			return nil, fmt.Errorf("解析buy_exact_in参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 15); err != nil {
+			return nil, err
+		}
+		ix.Accounts.fill(keys)
+		return ix, nil
+
+	case raydium_launchpad.Instruction_BuyExactOut:
+		ix := new(BuyExactOutInstruction)
+		if err := decodeFields(decoder, &ix.AmountOut, &ix.MaximumAmountIn, &ix.ShareFeeRate); err != nil {
+			return nil, fmt.Errorf("解析buy_exact_out参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 15); err != nil {
+			return nil, err
+		}
+		ix.Accounts.fill(keys)
+		return ix, nil
+
+	case raydium_launchpad.Instruction_SellExactIn:
+		ix := new(SellExactInInstruction)
+		if err := decodeFields(decoder, &ix.AmountIn, &ix.MinimumAmountOut, &ix.ShareFeeRate); err != nil {
+			return nil, fmt.Errorf("解析sell_exact_in参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 15); err != nil {
+			return nil, err
+		}
+		ix.Accounts.fill(keys)
+		return ix, nil
+
+	case raydium_launchpad.Instruction_SellExactOut:
+		ix := new(SellExactOutInstruction)
+		if err := decodeFields(decoder, &ix.AmountOut, &ix.MaximumAmountIn, &ix.ShareFeeRate); err != nil {
+			return nil, fmt.Errorf("解析sell_exact_out参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 15); err != nil {
+			return nil, err
+		}
+		ix.Accounts.fill(keys)
+		return ix, nil
+
+	case raydium_launchpad.Instruction_ClaimPlatformFee:
+		ix := new(ClaimPlatformFeeInstruction)
+		if err := checkAccounts(ix, keys, 10); err != nil {
+			return nil, err
+		}
+		ix.Accounts = ClaimPlatformFeeAccounts{
+			PlatformFeeWallet:      keys[0],
+			Authority:              keys[1],
+			PoolState:              keys[2],
+			PlatformConfig:         keys[3],
+			QuoteVault:             keys[4],
+			RecipientTokenAccount:  keys[5],
+			QuoteMint:              keys[6],
+			TokenProgram:           keys[7],
+			SystemProgram:          keys[8],
+			AssociatedTokenProgram: keys[9],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_ClaimVestedToken:
+		ix := new(ClaimVestedTokenInstruction)
+		if err := checkAccounts(ix, keys, 10); err != nil {
+			return nil, err
+		}
+		ix.Accounts = ClaimVestedTokenAccounts{
+			Beneficiary:            keys[0],
+			Authority:              keys[1],
+			PoolState:              keys[2],
+			VestingRecord:          keys[3],
+			BaseVault:              keys[4],
+			UserBaseToken:          keys[5],
+			BaseTokenMint:          keys[6],
+			BaseTokenProgram:       keys[7],
+			SystemProgram:          keys[8],
+			AssociatedTokenProgram: keys[9],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_CollectFee:
+		ix := new(CollectFeeInstruction)
+		if err := checkAccounts(ix, keys, 8); err != nil {
+			return nil, err
+		}
+		ix.Accounts.fill(keys)
+		return ix, nil
+
+	case raydium_launchpad.Instruction_CollectMigrateFee:
+		ix := new(CollectMigrateFeeInstruction)
+		if err := checkAccounts(ix, keys, 8); err != nil {
+			return nil, err
+		}
+		ix.Accounts.fill(keys)
+		return ix, nil
+
+	case raydium_launchpad.Instruction_CreateConfig:
+		ix := new(CreateConfigInstruction)
+		if err := decodeFields(decoder, &ix.CurveType, &ix.Index, &ix.MigrateFee, &ix.TradeFeeRate); err != nil {
+			return nil, fmt.Errorf("解析create_config参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 8); err != nil {
+			return nil, err
+		}
+		ix.Accounts = CreateConfigAccounts{
+			Owner:                 keys[0],
+			GlobalConfig:          keys[1],
+			QuoteTokenMint:        keys[2],
+			ProtocolFeeOwner:      keys[3],
+			MigrateFeeOwner:       keys[4],
+			MigrateToAmmWallet:    keys[5],
+			MigrateToCpswapWallet: keys[6],
+			SystemProgram:         keys[7],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_CreatePlatformConfig:
+		ix := new(CreatePlatformConfigInstruction)
+		if err := decoder.Decode(&ix.PlatformParams); err != nil {
+			return nil, fmt.Errorf("解析create_platform_config参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 5); err != nil {
+			return nil, err
+		}
+		ix.Accounts = CreatePlatformConfigAccounts{
+			PlatformAdmin:     keys[0],
+			PlatformFeeWallet: keys[1],
+			PlatformNftWallet: keys[2],
+			PlatformConfig:    keys[3],
+			SystemProgram:     keys[4],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_CreateVestingAccount:
+		ix := new(CreateVestingAccountInstruction)
+		if err := decoder.Decode(&ix.ShareAmount); err != nil {
+			return nil, fmt.Errorf("解析create_vesting_account参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 5); err != nil {
+			return nil, err
+		}
+		ix.Accounts = CreateVestingAccountAccounts{
+			Creator:       keys[0],
+			Beneficiary:   keys[1],
+			PoolState:     keys[2],
+			VestingRecord: keys[3],
+			SystemProgram: keys[4],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_Initialize:
+		ix := new(InitializeInstruction)
+		if err := decoder.Decode(&ix.MintParams); err != nil {
+			return nil, fmt.Errorf("解析MintParams失败: %w", err)
+		}
+		curveParams, err := raydium_launchpad.DecodeCurveParams(decoder)
+		if err != nil {
+			return nil, fmt.Errorf("解析CurveParams失败: %w", err)
+		}
+		curveType, err := GetCurveType(curveParams)
+		if err != nil {
+			return nil, err
+		}
+		ix.CurveType = curveType
+		ix.CurveParams = curveParams
+		if err := decoder.Decode(&ix.VestingParams); err != nil {
+			return nil, fmt.Errorf("解析VestingParams失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 18); err != nil {
+			return nil, err
+		}
+		ix.Accounts = InitializeInstructionAccounts{
+			Payer:             keys[0],
+			Creator:           keys[1],
+			GlobalConfig:      keys[2],
+			PlatformConfig:    keys[3],
+			Authority:         keys[4],
+			PoolState:         keys[5],
+			BaseMint:          keys[6],
+			QuoteMint:         keys[7],
+			BaseVault:         keys[8],
+			QuoteVault:        keys[9],
+			MetadataAccount:   keys[10],
+			BaseTokenProgram:  keys[11],
+			QuoteTokenProgram: keys[12],
+			MetadataProgram:   keys[13],
+			SystemProgram:     keys[14],
+			RentProgram:       keys[15],
+			EventAuthority:    keys[16],
+			Program:           keys[17],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_MigrateToAmm:
+		ix := new(MigrateToAmmInstruction)
+		if err := decodeFields(decoder, &ix.BaseLotSize, &ix.QuoteLotSize, &ix.MarketVaultSignerNonce); err != nil {
+			return nil, fmt.Errorf("解析migrate_to_amm参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 32); err != nil {
+			return nil, err
+		}
+		ix.Accounts = MigrateToAmmAccounts{
+			Payer:                   keys[0],
+			BaseMint:                keys[1],
+			QuoteMint:               keys[2],
+			OpenbookProgram:         keys[3],
+			Market:                  keys[4],
+			RequestQueue:            keys[5],
+			EventQueue:              keys[6],
+			Bids:                    keys[7],
+			Asks:                    keys[8],
+			MarketVaultSigner:       keys[9],
+			MarketBaseVault:         keys[10],
+			MarketQuoteVault:        keys[11],
+			AmmProgram:              keys[12],
+			AmmPool:                 keys[13],
+			AmmAuthority:            keys[14],
+			AmmOpenOrders:           keys[15],
+			AmmLpMint:               keys[16],
+			AmmBaseVault:            keys[17],
+			AmmQuoteVault:           keys[18],
+			AmmTargetOrders:         keys[19],
+			AmmConfig:               keys[20],
+			AmmCreateFeeDestination: keys[21],
+			Authority:               keys[22],
+			PoolState:               keys[23],
+			GlobalConfig:            keys[24],
+			BaseVault:               keys[25],
+			QuoteVault:              keys[26],
+			PoolLpToken:             keys[27],
+			SplTokenProgram:         keys[28],
+			AssociatedTokenProgram:  keys[29],
+			SystemProgram:           keys[30],
+			RentProgram:             keys[31],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_MigrateToCpswap:
+		ix := new(MigrateToCpswapInstruction)
+		if err := checkAccounts(ix, keys, 28); err != nil {
+			return nil, err
+		}
+		ix.Accounts = MigrateToCpswapAccounts{
+			Payer:                  keys[0],
+			BaseMint:               keys[1],
+			QuoteMint:              keys[2],
+			PlatformConfig:         keys[3],
+			CpswapProgram:          keys[4],
+			CpswapPool:             keys[5],
+			CpswapAuthority:        keys[6],
+			CpswapLpMint:           keys[7],
+			CpswapBaseVault:        keys[8],
+			CpswapQuoteVault:       keys[9],
+			CpswapConfig:           keys[10],
+			CpswapCreatePoolFee:    keys[11],
+			CpswapObservation:      keys[12],
+			LockProgram:            keys[13],
+			LockAuthority:          keys[14],
+			LockLpVault:            keys[15],
+			Authority:              keys[16],
+			PoolState:              keys[17],
+			GlobalConfig:           keys[18],
+			BaseVault:              keys[19],
+			QuoteVault:             keys[20],
+			PoolLpToken:            keys[21],
+			BaseTokenProgram:       keys[22],
+			QuoteTokenProgram:      keys[23],
+			AssociatedTokenProgram: keys[24],
+			SystemProgram:          keys[25],
+			RentProgram:            keys[26],
+			MetadataProgram:        keys[27],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_UpdateConfig:
+		ix := new(UpdateConfigInstruction)
+		if err := decodeFields(decoder, &ix.Param, &ix.Value); err != nil {
+			return nil, fmt.Errorf("解析update_config参数失败: %w", err)
+		}
+		if err := checkAccounts(ix, keys, 2); err != nil {
+			return nil, err
+		}
+		ix.Accounts = UpdateConfigAccounts{
+			Owner:        keys[0],
+			GlobalConfig: keys[1],
+		}
+		return ix, nil
+
+	case raydium_launchpad.Instruction_UpdatePlatformConfig:
+		ix := new(UpdatePlatformConfigInstruction)
+		param, err := raydium_launchpad.DecodePlatformConfigParam(decoder)
+		if err != nil {
+			return nil, fmt.Errorf("解析update_platform_config参数失败: %w", err)
+		}
+		ix.Param = param
+		if err := checkAccounts(ix, keys, 2); err != nil {
+			return nil, err
+		}
+		ix.Accounts = UpdatePlatformConfigAccounts{
+			PlatformAdmin:  keys[0],
+			PlatformConfig: keys[1],
+		}
+		return ix, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownInstruction, binary.FormatDiscriminator(discriminator))
+	}
+}
+
+// decodeFields 按顺序解析多个基础类型参数
+func decodeFields(decoder *binary.Decoder, fields ...any) error {
+	for _, field := range fields {
+		if err := decoder.Decode(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAccounts 检查指令的账户数量是否满足要求
+func checkAccounts(ix LaunchpadInstruction, keys []solana.PublicKey, want int) error {
+	if len(keys) < want {
+		return fmt.Errorf("%s 账户数量不足: 需要 %d, 实际 %d", ix.InstructionName(), want, len(keys))
+	}
+	return nil
+}
+
+// ProcessTransactionInstructions 解析交易中的全部launchpad指令, 包括通过CPI调用的指令
+func (p *PoolMonit) ProcessTransactionInstructions(signature solana.Signature) ([]*ParsedInstruction, error) {
+	transaction, transactionInfo, err := p.getTransaction(p.ctx, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ParsedInstruction
+	for _, ref := range flattenInstructions(transactionInfo, transaction.Meta) {
+		// 跳过 emit_cpi! 产生的事件自调用
+		if len(ref.Instruction.Data) >= 8 && bytes.Equal(ref.Instruction.Data[:8], EventIxTag[:]) {
+			continue
+		}
+		ix, err := DecodeInstruction(ref.Instruction, transactionInfo)
+		if err != nil {
+			if !errors.Is(err, ErrNotLaunchpadInstruction) {
+				log.Error(fmt.Sprintf("解析指令失败! 签名: %s, 指令索引: %d/%d |", signature, ref.Index, ref.InnerIndex), err)
+			}
+			continue
+		}
+		parsed := &ParsedInstruction{
+			Signature:    signature.String(),
+			Index:        ref.Index,
+			InnerIndex:   ref.InnerIndex,
+			Depth:        ref.Depth,
+			OuterProgram: ref.OuterProgram,
+			Name:         ix.InstructionName(),
+			Instruction:  ix,
+		}
+		if transaction.BlockTime != nil {
+			parsed.TransferTime = transaction.BlockTime.Time()
+		}
+		result = append(result, parsed)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("交易中没有launchpad指令")
+	}
+	return result, nil
+}
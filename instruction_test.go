@@ -0,0 +1,205 @@
+package bonk
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// builderAccounts 从生成的 idl/instructions.go 中读取每个 New*Instruction 的账户参数, 按 IDL 顺序转换为 snake_case
+func builderAccounts(t *testing.T) map[string][]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "idl/instructions.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string][]string)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		for _, field := range fn.Type.Params.List {
+			for _, name := range field.Names {
+				if account, ok := strings.CutSuffix(name.Name, "Account"); ok {
+					result[fn.Name.Name] = append(result[fn.Name.Name], snakeCase(account))
+				}
+			}
+		}
+	}
+	return result
+}
+
+func snakeCase(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func TestDecodeInstruction(t *testing.T) {
+	accountNames := builderAccounts(t)
+	// anchor-go 为没有参数的指令生成的 builder 不写入 discriminator, 测试时补上
+	noArgs := map[string][8]byte{
+		"claim_platform_fee":  raydium_launchpad.Instruction_ClaimPlatformFee,
+		"claim_vested_token":  raydium_launchpad.Instruction_ClaimVestedToken,
+		"collect_fee":         raydium_launchpad.Instruction_CollectFee,
+		"collect_migrate_fee": raydium_launchpad.Instruction_CollectMigrateFee,
+		"migrate_to_cpswap":   raydium_launchpad.Instruction_MigrateToCpswap,
+	}
+
+	tests := []struct {
+		builder any   // raydium_launchpad.New*Instruction
+		params  []any // 账户之前的参数
+		want    LaunchpadInstruction
+	}{
+		{raydium_launchpad.NewBuyExactInInstruction, []any{uint64(1), uint64(2), uint64(3)},
+			&BuyExactInInstruction{AmountIn: 1, MinimumAmountOut: 2, ShareFeeRate: 3}},
+		{raydium_launchpad.NewBuyExactOutInstruction, []any{uint64(1), uint64(2), uint64(3)},
+			&BuyExactOutInstruction{AmountOut: 1, MaximumAmountIn: 2, ShareFeeRate: 3}},
+		{raydium_launchpad.NewSellExactInInstruction, []any{uint64(1), uint64(2), uint64(3)},
+			&SellExactInInstruction{AmountIn: 1, MinimumAmountOut: 2, ShareFeeRate: 3}},
+		{raydium_launchpad.NewSellExactOutInstruction, []any{uint64(1), uint64(2), uint64(3)},
+			&SellExactOutInstruction{AmountOut: 1, MaximumAmountIn: 2, ShareFeeRate: 3}},
+		{raydium_launchpad.NewClaimPlatformFeeInstruction, nil, &ClaimPlatformFeeInstruction{}},
+		{raydium_launchpad.NewClaimVestedTokenInstruction, nil, &ClaimVestedTokenInstruction{}},
+		{raydium_launchpad.NewCollectFeeInstruction, nil, &CollectFeeInstruction{}},
+		{raydium_launchpad.NewCollectMigrateFeeInstruction, nil, &CollectMigrateFeeInstruction{}},
+		{raydium_launchpad.NewCreateConfigInstruction, []any{uint8(2), uint16(1), uint64(3), uint64(4)},
+			&CreateConfigInstruction{CurveType: 2, Index: 1, MigrateFee: 3, TradeFeeRate: 4}},
+		{raydium_launchpad.NewCreatePlatformConfigInstruction, []any{raydium_launchpad.PlatformParams{
+			MigrateNftInfo: raydium_launchpad.MigrateNftInfo{PlatformScale: 1, CreatorScale: 2, BurnScale: 3},
+			FeeRate:        10000,
+			Name:           "bonk",
+			Web:            "https://bonk.fun",
+			Img:            "https://bonk.fun/logo.png",
+		}}, &CreatePlatformConfigInstruction{PlatformParams: raydium_launchpad.PlatformParams{
+			MigrateNftInfo: raydium_launchpad.MigrateNftInfo{PlatformScale: 1, CreatorScale: 2, BurnScale: 3},
+			FeeRate:        10000,
+			Name:           "bonk",
+			Web:            "https://bonk.fun",
+			Img:            "https://bonk.fun/logo.png",
+		}}},
+		{raydium_launchpad.NewCreateVestingAccountInstruction, []any{uint64(5)},
+			&CreateVestingAccountInstruction{ShareAmount: 5}},
+		{raydium_launchpad.NewInitializeInstruction, []any{
+			raydium_launchpad.MintParams{Decimals: 6, Name: "Bonk", Symbol: "BONK", Uri: "https://bonk.fun/meta.json"},
+			&raydium_launchpad.CurveParams_Linear{Data: raydium_launchpad.LinearCurve{Supply: 1, TotalQuoteFundRaising: 2, MigrateType: 1}},
+			raydium_launchpad.VestingParams{TotalLockedAmount: 3, CliffPeriod: 4, UnlockPeriod: 5},
+		}, &InitializeInstruction{
+			MintParams:    raydium_launchpad.MintParams{Decimals: 6, Name: "Bonk", Symbol: "BONK", Uri: "https://bonk.fun/meta.json"},
+			CurveType:     CurveType_Linear,
+			CurveParams:   &raydium_launchpad.CurveParams_Linear{Data: raydium_launchpad.LinearCurve{Supply: 1, TotalQuoteFundRaising: 2, MigrateType: 1}},
+			VestingParams: raydium_launchpad.VestingParams{TotalLockedAmount: 3, CliffPeriod: 4, UnlockPeriod: 5},
+		}},
+		{raydium_launchpad.NewMigrateToAmmInstruction, []any{uint64(1), uint64(2), uint8(3)},
+			&MigrateToAmmInstruction{BaseLotSize: 1, QuoteLotSize: 2, MarketVaultSignerNonce: 3}},
+		{raydium_launchpad.NewMigrateToCpswapInstruction, nil, &MigrateToCpswapInstruction{}},
+		{raydium_launchpad.NewUpdateConfigInstruction, []any{uint8(1), uint64(2)},
+			&UpdateConfigInstruction{Param: 1, Value: 2}},
+		{raydium_launchpad.NewUpdatePlatformConfigInstruction, []any{raydium_launchpad.PlatformConfigParam(&raydium_launchpad.PlatformConfigParam_FeeRate{V0: 3})},
+			&UpdatePlatformConfigInstruction{Param: &raydium_launchpad.PlatformConfigParam_FeeRate{V0: 3}}},
+	}
+	for _, tt := range tests {
+		builder := reflect.ValueOf(tt.builder)
+		builderName := runtime.FuncForPC(builder.Pointer()).Name()
+		builderName = builderName[strings.LastIndex(builderName, ".")+1:]
+
+		t.Run(tt.want.InstructionName(), func(t *testing.T) {
+			// 每个账户使用不同的地址, 顺序错误时解析出的字段会不一致
+			names := accountNames[builderName]
+			keys := make([]solana.PublicKey, len(names))
+			args := make([]reflect.Value, 0, builder.Type().NumIn())
+			for _, param := range tt.params {
+				args = append(args, reflect.ValueOf(param))
+			}
+			for i := range keys {
+				keys[i] = solana.NewWallet().PublicKey()
+				args = append(args, reflect.ValueOf(keys[i]))
+			}
+			out := builder.Call(args)
+			if err, _ := out[1].Interface().(error); err != nil {
+				t.Fatal(err)
+			}
+			instruction := out[0].Interface().(solana.Instruction)
+			if discriminator, ok := noArgs[tt.want.InstructionName()]; ok {
+				instruction = solana.NewInstruction(instruction.ProgramID(), instruction.Accounts(), discriminator[:])
+			}
+			transaction, err := solana.NewTransaction([]solana.Instruction{instruction},
+				solana.Hash{}, solana.TransactionPayer(solana.NewWallet().PublicKey()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := DecodeInstruction(transaction.Message.Instructions[0], transaction)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Fatalf("DecodeInstruction() = %T, want %T", got, tt.want)
+			}
+
+			// 账户结构体的字段按 IDL 的顺序声明, json 名称与 IDL 一致
+			accounts := reflect.ValueOf(got).Elem().FieldByName("Accounts")
+			if accounts.NumField() != len(keys) {
+				t.Fatalf("%d accounts, IDL has %d", accounts.NumField(), len(keys))
+			}
+			for i := range keys {
+				field := accounts.Type().Field(i)
+				if tag := field.Tag.Get("json"); tag != names[i] {
+					t.Errorf("account %d = %s, IDL name %s", i, tag, names[i])
+				}
+				if value := accounts.Field(i).Interface().(solana.PublicKey); !value.Equals(keys[i]) {
+					t.Errorf("account %d %s = %s, want %s", i, field.Name, value, keys[i])
+				}
+			}
+
+			accounts.Set(reflect.Zero(accounts.Type()))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeInstruction() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInstructionErrors(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	instruction, err := raydium_launchpad.NewCollectFeeInstruction(payer, payer, payer, payer, payer, payer, payer, payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := raydium_launchpad.Instruction_CollectFee[:]
+
+	other := solana.NewInstruction(solana.SystemProgramID, instruction.Accounts(), data)
+	short := solana.NewInstruction(raydium_launchpad.ProgramID, instruction.Accounts()[:7], data)
+	unknown := solana.NewInstruction(raydium_launchpad.ProgramID, instruction.Accounts(), make([]byte, 8))
+	transaction, err := solana.NewTransaction([]solana.Instruction{other, short, unknown}, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DecodeInstruction(transaction.Message.Instructions[0], transaction); err != ErrNotLaunchpadInstruction {
+		t.Errorf("system instruction error = %v, want ErrNotLaunchpadInstruction", err)
+	}
+	if _, err := DecodeInstruction(transaction.Message.Instructions[1], transaction); err == nil {
+		t.Error("missing accounts error = nil")
+	}
+	if _, err := DecodeInstruction(transaction.Message.Instructions[2], transaction); !errors.Is(err, ErrUnknownInstruction) {
+		t.Errorf("unknown discriminator error = %v, want ErrUnknownInstruction", err)
+	}
+}
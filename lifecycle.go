@@ -0,0 +1,295 @@
+package bonk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MigrateType 募集完成后迁移的目标, 取值与 PoolState.MigrateType 一致
+type MigrateType uint8
+
+const (
+	MigrateType_Amm    MigrateType = iota // Raydium AMM v4
+	MigrateType_Cpswap                    // Raydium CPSWAP
+)
+
+func (t MigrateType) String() string {
+	switch t {
+	case MigrateType_Amm:
+		return "AMM"
+	case MigrateType_Cpswap:
+		return "CPSWAP"
+	default:
+		return ""
+	}
+}
+
+// PoolLifecycleEvent 池子状态变化事件, *PoolFundingCompleted / *PoolMigrated
+type PoolLifecycleEvent interface {
+	EventName() string
+	transition() *PoolTransition
+}
+
+// PoolTransition 状态变化时池子的信息
+type PoolTransition struct {
+	PoolId      solana.PublicKey             `json:"pool_id"`
+	Slot        uint64                       `json:"slot"`
+	Signature   solana.Signature             `json:"signature"` // 引起变化的交易, 无法确定时为空
+	BaseMint    solana.PublicKey             `json:"base_mint"`
+	QuoteMint   solana.PublicKey             `json:"quote_mint"`
+	MigrateType MigrateType                  `json:"migrate_type"`
+	RealBase    uint64                       `json:"real_base"`  // 变化时池子中的 base 数量
+	RealQuote   uint64                       `json:"real_quote"` // 变化时池子中的 quote 数量
+	PoolState   *raydium_launchpad.PoolState `json:"pool_state"`
+}
+
+func (t *PoolTransition) transition() *PoolTransition { return t }
+
+// PoolFundingCompleted 募集完成, 状态从 Fund 变为 Migrate
+type PoolFundingCompleted struct {
+	PoolTransition
+}
+
+func (*PoolFundingCompleted) EventName() string { return "PoolFundingCompleted" }
+
+// PoolMigrated 迁移完成, 状态变为 Trade
+type PoolMigrated struct {
+	PoolTransition
+	Migration *Migration `json:"migration"` // 迁移交易解析出的新池子, 找不到迁移交易时为 nil
+}
+
+func (*PoolMigrated) EventName() string { return "PoolMigrated" }
+
+// lifecycleState 池子最后的状态
+type lifecycleState struct {
+	slot   uint64
+	status raydium_launchpad.PoolStatus
+}
+
+// LifecycleTracker 根据 PoolState 的变化输出池子生命周期事件
+//
+// 池子的数据来自 PoolRegistry 或 PoolWatcher, 第一次看到的池子只记录状态, 之后状态前进时输出事件;
+// 错过中间状态时(例如直接从 Fund 变为 Trade)会依次输出两个事件
+type LifecycleTracker struct {
+	RpcClient  *rpc.Client        // 用于查询引起变化的交易, 为 nil 时不查询
+	Commitment rpc.CommitmentType // 默认 confirmed
+	Retry      ReconnectPolicy    // 交易还没有被索引时的重试策略, 默认 DefaultTransactionRetry
+
+	Events chan PoolLifecycleEvent // Run 返回后关闭
+
+	lock    sync.Mutex
+	pools   map[solana.PublicKey]*lifecycleState
+	pending chan PoolLifecycleEvent
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// NewLifecycleTracker 创建生命周期跟踪器, rpcClient 为 nil 时事件不包含交易签名
+func NewLifecycleTracker(rpcClient *rpc.Client) *LifecycleTracker {
+	return &LifecycleTracker{
+		RpcClient:  rpcClient,
+		Commitment: rpc.CommitmentConfirmed,
+		Retry:      DefaultTransactionRetry,
+		Events:     make(chan PoolLifecycleEvent, 1000),
+		pools:      make(map[solana.PublicKey]*lifecycleState),
+		pending:    make(chan PoolLifecycleEvent, 1000),
+		done:       make(chan struct{}),
+	}
+}
+
+// TrackRegistry 跟踪注册表中全部池子的变化
+func (t *LifecycleTracker) TrackRegistry(registry *PoolRegistry) {
+	registry.OnChange(func(prev, cur *RegisteredPool) {
+		if cur == nil {
+			t.Forget(prev.PoolId)
+			return
+		}
+		t.Observe(cur.PoolId, cur.Slot, cur.PoolState)
+	})
+}
+
+// Observe 记录池子的状态, 状态前进时生成事件
+//
+// Observe 在注册表或监听器的协程中调用, 不会阻塞: Run 没有运行或待处理的事件超过 1000 个时丢弃事件, 见 Dropped
+func (t *LifecycleTracker) Observe(poolId solana.PublicKey, slot uint64, poolState *raydium_launchpad.PoolState) {
+	status := raydium_launchpad.PoolStatus(poolState.Status)
+
+	t.lock.Lock()
+	state, ok := t.pools[poolId]
+	if !ok {
+		t.pools[poolId] = &lifecycleState{slot: slot, status: status}
+		t.lock.Unlock()
+		return
+	}
+	if slot < state.slot || status <= state.status {
+		t.lock.Unlock()
+		return
+	}
+	prev := state.status
+	state.slot = slot
+	state.status = status
+	t.lock.Unlock()
+
+	transition := PoolTransition{
+		PoolId:      poolId,
+		Slot:        slot,
+		BaseMint:    poolState.BaseMint,
+		QuoteMint:   poolState.QuoteMint,
+		MigrateType: MigrateType(poolState.MigrateType),
+		RealBase:    poolState.RealBase,
+		RealQuote:   poolState.RealQuote,
+		PoolState:   poolState,
+	}
+	var events []PoolLifecycleEvent
+	if prev < raydium_launchpad.PoolStatus_Migrate {
+		events = append(events, &PoolFundingCompleted{PoolTransition: transition})
+	}
+	if status >= raydium_launchpad.PoolStatus_Trade {
+		events = append(events, &PoolMigrated{PoolTransition: transition})
+	}
+	for _, event := range events {
+		select {
+		case <-t.done:
+			return
+		case t.pending <- event:
+		default:
+			t.dropped.Add(1)
+			log.Printf("待处理的生命周期事件过多, 丢弃池子 %s 的 %s 事件", poolId, event.EventName())
+		}
+	}
+}
+
+// Dropped 待处理的事件过多时丢弃的事件数量
+func (t *LifecycleTracker) Dropped() uint64 {
+	return t.dropped.Load()
+}
+
+// Forget 不再跟踪池子
+func (t *LifecycleTracker) Forget(poolId solana.PublicKey) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.pools, poolId)
+}
+
+// Run 查询引起变化的交易并输出事件, 直到 ctx 结束, 返回后关闭 Events
+func (t *LifecycleTracker) Run(ctx context.Context) error {
+	defer close(t.Events)
+	defer close(t.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-t.pending:
+			if t.RpcClient != nil {
+				if err := t.resolve(ctx, event); err != nil {
+					log.Printf("查询池子 %s 的交易失败 | %v", event.transition().PoolId, err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case t.Events <- event:
+			}
+		}
+	}
+}
+
+// resolve 查找引起变化的交易, 迁移事件会解析迁移交易并关联迁移后的池子
+func (t *LifecycleTracker) resolve(ctx context.Context, event PoolLifecycleEvent) error {
+	transition := event.transition()
+	signatures, err := t.signaturesAt(ctx, transition.PoolId, transition.Slot)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if migrated, ok := event.(*PoolMigrated); ok {
+		fetcher := &transactionFetcher{
+			client:     t.RpcClient,
+			commitment: t.Commitment,
+			policy:     t.Retry,
+			stats:      &transactionStats{},
+		}
+		for _, signature := range signatures {
+			transaction, err := fetcher.fetch(ctx, signature)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			migrations, err := ExtractMigrations(signature.String(), transaction)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, migration := range migrations {
+				if migration.PoolState.Equals(transition.PoolId) {
+					transition.Signature = signature
+					migrated.Migration = migration
+					return nil
+				}
+			}
+		}
+	}
+
+	// 该 slot 中没有或有多笔成功的交易时无法确定
+	if len(signatures) == 1 {
+		transition.Signature = signatures[0]
+	}
+	return errors.Join(errs...)
+}
+
+// signaturesAt 查询池子在 slot 中成功执行的交易, 还没有索引到该 slot 时按 Retry 重试
+func (t *LifecycleTracker) signaturesAt(ctx context.Context, poolId solana.PublicKey, slot uint64) ([]solana.Signature, error) {
+	policy := t.Retry.withDefaults()
+	if policy.MaxFailures <= 0 {
+		policy.MaxFailures = DefaultTransactionRetry.MaxFailures
+	}
+	limit := 100
+
+	for attempt := 1; ; attempt++ {
+		signatures, err := t.RpcClient.GetSignaturesForAddressWithOpts(ctx, poolId, &rpc.GetSignaturesForAddressOpts{
+			Limit:      &limit,
+			Commitment: t.Commitment,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取交易签名失败: %w", err)
+		}
+
+		var (
+			found   []solana.Signature
+			indexed bool // 已经索引到该 slot
+		)
+		for _, signature := range signatures {
+			if signature.Slot >= slot {
+				indexed = true
+			}
+			if signature.Slot == slot && signature.Err == nil {
+				found = append(found, signature.Signature)
+			}
+		}
+		if indexed {
+			return found, nil
+		}
+		if policy.exhausted(attempt) {
+			return nil, fmt.Errorf("重试 %d 次后仍未索引到 slot %d", attempt-1, slot)
+		}
+
+		timer := time.NewTimer(policy.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
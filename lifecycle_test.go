@@ -0,0 +1,44 @@
+package bonk
+
+import (
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestLifecycleTrackerObserve(t *testing.T) {
+	tracker := NewLifecycleTracker(nil)
+	poolId := solana.NewWallet().PublicKey()
+	state := func(status raydium_launchpad.PoolStatus) *raydium_launchpad.PoolState {
+		return &raydium_launchpad.PoolState{Status: uint8(status)}
+	}
+
+	tracker.Observe(poolId, 10, state(raydium_launchpad.PoolStatus_Fund))
+	if len(tracker.pending) != 0 {
+		t.Fatalf("pending = %d, want 0 for the first observation", len(tracker.pending))
+	}
+	// 错过 Migrate 时依次生成两个事件, 旧的 slot 被忽略
+	tracker.Observe(poolId, 12, state(raydium_launchpad.PoolStatus_Trade))
+	tracker.Observe(poolId, 11, state(raydium_launchpad.PoolStatus_Migrate))
+	if len(tracker.pending) != 2 {
+		t.Fatalf("pending = %d, want 2", len(tracker.pending))
+	}
+	if _, ok := (<-tracker.pending).(*PoolFundingCompleted); !ok {
+		t.Error("first event is not PoolFundingCompleted")
+	}
+	if _, ok := (<-tracker.pending).(*PoolMigrated); !ok {
+		t.Error("second event is not PoolMigrated")
+	}
+
+	// Run 没有运行时队列满后丢弃, 不阻塞调用方
+	for i := range cap(tracker.pending) + 5 {
+		id := solana.NewWallet().PublicKey()
+		tracker.Observe(id, uint64(i), state(raydium_launchpad.PoolStatus_Fund))
+		tracker.Observe(id, uint64(i)+1, state(raydium_launchpad.PoolStatus_Migrate))
+	}
+	if dropped := tracker.Dropped(); dropped != 5 {
+		t.Errorf("Dropped() = %d, want 5", dropped)
+	}
+}
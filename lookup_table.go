@@ -0,0 +1,143 @@
+package bonk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// needLookups 判断交易是否使用了地址查找表且尚未解析
+func needLookups(transaction *solana.Transaction) bool {
+	return transaction.Message.IsVersioned() &&
+		transaction.Message.AddressTableLookups.NumLookups() > 0 &&
+		!transaction.Message.IsResolved()
+}
+
+// ResolveLoadedAddresses 使用交易meta中的 LoadedAddresses 解析v0交易的地址查找表
+//
+// 解析成功后 transaction.Message.AccountKeys 会包含全部账户(静态账户 + 可写查找账户 + 只读查找账户)
+func ResolveLoadedAddresses(transaction *solana.Transaction, meta *rpc.TransactionMeta) error {
+	if !needLookups(transaction) {
+		return nil
+	}
+	if meta == nil {
+		return errors.New("交易meta为空")
+	}
+
+	lookups := transaction.Message.AddressTableLookups
+	loaded := meta.LoadedAddresses
+	if len(loaded.Writable) != lookups.NumWritableLookups() ||
+		len(loaded.Writable)+len(loaded.ReadOnly) != lookups.NumLookups() {
+		return fmt.Errorf("LoadedAddresses 与查找表数量不一致: writable %d, readonly %d, lookups %d",
+			len(loaded.Writable), len(loaded.ReadOnly), lookups.NumLookups())
+	}
+
+	// LoadedAddresses 按查找表顺序依次排列, 还原成只包含被引用下标的稀疏表
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice)
+	put := func(table solana.PublicKey, index uint8, key solana.PublicKey) {
+		addresses := tables[table]
+		for len(addresses) <= int(index) {
+			addresses = append(addresses, solana.PublicKey{})
+		}
+		addresses[index] = key
+		tables[table] = addresses
+	}
+	var writable, readonly int
+	for _, lookup := range lookups {
+		for _, index := range lookup.WritableIndexes {
+			put(lookup.AccountKey, index, loaded.Writable[writable])
+			writable++
+		}
+	}
+	for _, lookup := range lookups {
+		for _, index := range lookup.ReadonlyIndexes {
+			put(lookup.AccountKey, index, loaded.ReadOnly[readonly])
+			readonly++
+		}
+	}
+	return setAddressTables(transaction, tables)
+}
+
+// setAddressTables 设置查找表并将查找账户追加到 AccountKeys
+//
+// SetAddressTables 只能调用一次, 之前设置过查找表但没有解析成功时(例如 LoadedAddresses 不完整)替换其中的内容
+func setAddressTables(transaction *solana.Transaction, tables map[solana.PublicKey]solana.PublicKeySlice) error {
+	if current := transaction.Message.GetAddressTables(); current != nil {
+		clear(current)
+		maps.Copy(current, tables)
+	} else if err := transaction.Message.SetAddressTables(tables); err != nil {
+		return fmt.Errorf("设置地址查找表失败: %w", err)
+	}
+	if err := transaction.Message.ResolveLookups(); err != nil {
+		return fmt.Errorf("解析地址查找表失败: %w", err)
+	}
+	return nil
+}
+
+// resolveAddressTables 解析v0交易的地址查找表
+//
+// 优先使用meta中的 LoadedAddresses, 不可用时从链上获取查找表账户并缓存
+func (p *PoolMonit) resolveAddressTables(ctx context.Context, transaction *solana.Transaction, meta *rpc.TransactionMeta) error {
+	if !needLookups(transaction) {
+		return nil
+	}
+	if err := ResolveLoadedAddresses(transaction, meta); err == nil {
+		return nil
+	}
+
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice)
+	for _, lookup := range transaction.Message.AddressTableLookups {
+		addresses, err := p.getLookupTable(ctx, lookup)
+		if err != nil {
+			return err
+		}
+		tables[lookup.AccountKey] = addresses
+	}
+	return setAddressTables(transaction, tables)
+}
+
+// getLookupTable 获取查找表的地址列表, 缓存中的表长度不足时重新获取(查找表只会追加)
+func (p *PoolMonit) getLookupTable(ctx context.Context, lookup solana.MessageAddressTableLookup) (solana.PublicKeySlice, error) {
+	maxIndex := 0
+	for _, index := range append(append([]uint8{}, lookup.WritableIndexes...), lookup.ReadonlyIndexes...) {
+		if int(index) > maxIndex {
+			maxIndex = int(index)
+		}
+	}
+
+	p.lookupLock.RLock()
+	addresses, ok := p.lookupTables[lookup.AccountKey]
+	p.lookupLock.RUnlock()
+	if ok && len(addresses) > maxIndex {
+		return addresses, nil
+	}
+
+	accounts, err := loadAccounts(ctx, p.Accounts, p.GetClient(), lookup.AccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("获取地址查找表 %s 失败: %w", lookup.AccountKey, err)
+	}
+	if len(accounts) != 1 || accounts[0] == nil {
+		return nil, fmt.Errorf("地址查找表 %s 不存在", lookup.AccountKey)
+	}
+	state, err := addresslookuptable.DecodeAddressLookupTableState(accountData(accounts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("解析地址查找表 %s 失败: %w", lookup.AccountKey, err)
+	}
+	if len(state.Addresses) <= maxIndex {
+		return nil, fmt.Errorf("地址查找表 %s 长度不足: %d <= %d", lookup.AccountKey, len(state.Addresses), maxIndex)
+	}
+
+	p.lookupLock.Lock()
+	if p.lookupTables == nil {
+		p.lookupTables = make(map[solana.PublicKey]solana.PublicKeySlice)
+	}
+	p.lookupTables[lookup.AccountKey] = state.Addresses
+	p.lookupLock.Unlock()
+	return state.Addresses, nil
+}
@@ -0,0 +1,205 @@
+package bonk
+
+import (
+	"context"
+	"testing"
+
+	binary "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/gosolana"
+)
+
+// newV0Transaction 构建使用两个查找表的v0交易, 返回重新解码的交易(未解析查找表)、查找表和指令账户
+func newV0Transaction(t *testing.T) (*solana.Transaction, map[solana.PublicKey]solana.PublicKeySlice, solana.AccountMetaSlice) {
+	t.Helper()
+	newKeys := func(n int) solana.PublicKeySlice {
+		keys := make(solana.PublicKeySlice, n)
+		for i := range keys {
+			keys[i] = solana.NewWallet().PublicKey()
+		}
+		return keys
+	}
+	payer := solana.NewWallet().PublicKey()
+	tables := map[solana.PublicKey]solana.PublicKeySlice{
+		solana.NewWallet().PublicKey(): newKeys(4),
+		solana.NewWallet().PublicKey(): newKeys(3),
+	}
+	var metas solana.AccountMetaSlice
+	metas = append(metas, solana.Meta(payer).WRITE().SIGNER(), solana.Meta(solana.NewWallet().PublicKey()))
+	for _, addresses := range tables {
+		metas = append(metas, solana.Meta(addresses[2]).WRITE(), solana.Meta(addresses[0]), solana.Meta(addresses[1]).WRITE())
+	}
+
+	instruction := solana.NewInstruction(solana.NewWallet().PublicKey(), metas, []byte{1})
+	built, err := solana.NewTransaction([]solana.Instruction{instruction}, solana.Hash{},
+		solana.TransactionPayer(payer), solana.TransactionAddressTables(tables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := built.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transaction, err := solana.TransactionFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !needLookups(transaction) {
+		t.Fatal("transaction does not use address lookup tables")
+	}
+	return transaction, tables, metas
+}
+
+// loadedAddresses 按节点返回 meta 的方式排列查找到的地址: 先全部可写, 再全部只读
+func loadedAddresses(transaction *solana.Transaction, tables map[solana.PublicKey]solana.PublicKeySlice) rpc.LoadedAddresses {
+	var loaded rpc.LoadedAddresses
+	for _, lookup := range transaction.Message.AddressTableLookups {
+		for _, index := range lookup.WritableIndexes {
+			loaded.Writable = append(loaded.Writable, tables[lookup.AccountKey][index])
+		}
+	}
+	for _, lookup := range transaction.Message.AddressTableLookups {
+		for _, index := range lookup.ReadonlyIndexes {
+			loaded.ReadOnly = append(loaded.ReadOnly, tables[lookup.AccountKey][index])
+		}
+	}
+	return loaded
+}
+
+// checkResolved 检查指令账户解析后与构建时一致
+func checkResolved(t *testing.T, transaction *solana.Transaction, metas solana.AccountMetaSlice) {
+	t.Helper()
+	if !transaction.Message.IsResolved() {
+		t.Fatal("message not resolved")
+	}
+	resolved, err := transaction.Message.Instructions[0].ResolveInstructionAccounts(&transaction.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != len(metas) {
+		t.Fatalf("%d accounts, want %d", len(resolved), len(metas))
+	}
+	for i, meta := range metas {
+		if !resolved[i].PublicKey.Equals(meta.PublicKey) || resolved[i].IsWritable != meta.IsWritable {
+			t.Errorf("account %d = %s writable=%v, want %s writable=%v",
+				i, resolved[i].PublicKey, resolved[i].IsWritable, meta.PublicKey, meta.IsWritable)
+		}
+	}
+}
+
+func TestResolveLoadedAddresses(t *testing.T) {
+	transaction, tables, metas := newV0Transaction(t)
+	meta := &rpc.TransactionMeta{LoadedAddresses: loadedAddresses(transaction, tables)}
+	if err := ResolveLoadedAddresses(transaction, meta); err != nil {
+		t.Fatal(err)
+	}
+	checkResolved(t, transaction, metas)
+	// 已经解析过的交易不再处理
+	if err := ResolveLoadedAddresses(transaction, nil); err != nil {
+		t.Errorf("ResolveLoadedAddresses() on resolved transaction = %v", err)
+	}
+
+	transaction, tables, _ = newV0Transaction(t)
+	if err := ResolveLoadedAddresses(transaction, nil); err == nil {
+		t.Error("ResolveLoadedAddresses(nil meta) error = nil")
+	}
+	loaded := loadedAddresses(transaction, tables)
+	loaded.ReadOnly = loaded.ReadOnly[1:]
+	if err := ResolveLoadedAddresses(transaction, &rpc.TransactionMeta{LoadedAddresses: loaded}); err == nil {
+		t.Error("ResolveLoadedAddresses() with missing addresses error = nil")
+	}
+	if transaction.Message.IsResolved() {
+		t.Error("message resolved with missing addresses")
+	}
+
+	// 旧版交易不需要解析
+	legacy, err := solana.NewTransaction([]solana.Instruction{solana.NewInstruction(solana.SystemProgramID, metas[:2], nil)},
+		solana.Hash{}, solana.TransactionPayer(metas[0].PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveLoadedAddresses(legacy, nil); err != nil {
+		t.Errorf("ResolveLoadedAddresses(legacy) = %v", err)
+	}
+}
+
+func TestResolveAddressTablesFallback(t *testing.T) {
+	transaction, tables, metas := newV0Transaction(t)
+
+	// 之前的解析设置了不完整的查找表, 之后从缓存的查找表重新解析
+	broken := make(map[solana.PublicKey]solana.PublicKeySlice)
+	for key := range tables {
+		broken[key] = tables[key][:1]
+	}
+	if err := setAddressTables(transaction, broken); err == nil {
+		t.Fatal("setAddressTables() with short tables error = nil")
+	}
+
+	monit := &PoolMonit{lookupTables: tables}
+	if err := monit.resolveAddressTables(context.Background(), transaction, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkResolved(t, transaction, metas)
+}
+
+// fakeAccountLoader 从内存读取账户的 AccountLoader
+type fakeAccountLoader struct {
+	accounts map[solana.PublicKey]*rpc.Account
+	loads    int
+}
+
+func (f *fakeAccountLoader) LoadAccounts(ctx context.Context, keys ...solana.PublicKey) ([]*rpc.Account, error) {
+	f.loads++
+	result := make([]*rpc.Account, len(keys))
+	for i, key := range keys {
+		result[i] = f.accounts[key]
+	}
+	return result, nil
+}
+
+func lookupTableAccount(t *testing.T, addresses solana.PublicKeySlice) *rpc.Account {
+	t.Helper()
+	data, err := binary.MarshalBin(addresslookuptable.AddressLookupTableState{
+		TypeIndex:        1,
+		DeactivationSlot: ^uint64(0),
+		Addresses:        addresses,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(data)}
+}
+
+func TestResolveAddressTablesLoader(t *testing.T) {
+	transaction, tables, metas := newV0Transaction(t)
+	loader := &fakeAccountLoader{accounts: make(map[solana.PublicKey]*rpc.Account)}
+	for key, addresses := range tables {
+		loader.accounts[key] = lookupTableAccount(t, addresses)
+	}
+
+	// 查找表通过 Accounts 读取, 缓存后不再读取
+	monit := &PoolMonit{Wallet: &gosolana.Wallet{}, Accounts: loader}
+	if err := monit.resolveAddressTables(context.Background(), transaction, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkResolved(t, transaction, metas)
+	loads := loader.loads
+	if loads != len(tables) {
+		t.Errorf("%d loads, want %d", loads, len(tables))
+	}
+	for _, lookup := range transaction.Message.AddressTableLookups {
+		if _, err := monit.getLookupTable(context.Background(), lookup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loader.loads != loads {
+		t.Errorf("cached tables loaded again")
+	}
+
+	transaction, _, _ = newV0Transaction(t)
+	if err := (&PoolMonit{Wallet: &gosolana.Wallet{}, Accounts: loader}).resolveAddressTables(context.Background(), transaction, nil); err == nil {
+		t.Error("resolveAddressTables() with missing table error = nil")
+	}
+}
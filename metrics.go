@@ -0,0 +1,62 @@
+package bonk
+
+import (
+	"errors"
+	"math"
+	"math/big"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+)
+
+// PoolMetrics 池子的募集进度和价格指标
+type PoolMetrics struct {
+	FundingPercent float64 `json:"funding_percent"` // RealQuote 占 TotalQuoteFundRaising 的百分比, 募集完成后为100
+	BaseRemaining  uint64  `json:"base_remaining"`  // 距离 TotalBaseSell 还可以卖出的 base 数量, 募集完成后为0
+	QuoteRemaining uint64  `json:"quote_remaining"` // 买完剩余 base 还需要的 quote 数量, 按曲线计算, 不含手续费, 溢出时为最大值
+	SpotPrice      float64 `json:"spot_price"`      // 每个 base 代币的 quote 价格, 已按小数位换算
+	MarketCap      float64 `json:"market_cap"`      // SpotPrice * Supply, 以 quote 代币计
+}
+
+// CalculatePoolMetrics 根据 PoolState 和曲线类型计算募集进度和价格指标
+func CalculatePoolMetrics(pool *raydium_launchpad.PoolState, curveType CurveType) (*PoolMetrics, error) {
+	if pool == nil {
+		return nil, errors.New("池子状态为空")
+	}
+	calculator, err := getCurveCalculator(curveType)
+	if err != nil {
+		return nil, err
+	}
+
+	// 募集完成后不能再买入
+	metrics := &PoolMetrics{FundingPercent: 100}
+	if raydium_launchpad.PoolStatus(pool.Status) == raydium_launchpad.PoolStatus_Fund {
+		if pool.TotalQuoteFundRaising > 0 && pool.RealQuote < pool.TotalQuoteFundRaising {
+			metrics.FundingPercent = float64(pool.RealQuote) / float64(pool.TotalQuoteFundRaising) * 100
+		}
+		if remaining := remainingBase(pool); remaining.Sign() > 0 {
+			metrics.BaseRemaining = remaining.Uint64()
+			quoteIn, err := calculator.buyExactOut(pool, remaining)
+			if err != nil {
+				return nil, err
+			}
+			metrics.QuoteRemaining = math.MaxUint64
+			if quoteIn.IsUint64() {
+				metrics.QuoteRemaining = quoteIn.Uint64()
+			}
+		}
+	}
+
+	price, err := SpotPrice(pool, curveType)
+	if err != nil {
+		return nil, err
+	}
+	metrics.SpotPrice = price
+	supply := new(big.Float).Quo(new(big.Float).SetInt(u64(pool.Supply)), pow10(pool.BaseDecimals))
+	metrics.MarketCap, _ = supply.Mul(supply, big.NewFloat(price)).Float64()
+	return metrics, nil
+}
+
+// Metrics 池子当前的募集进度和价格指标
+func (q *Quoter) Metrics() (*PoolMetrics, error) {
+	return CalculatePoolMetrics(q.Pool, q.CurveType)
+}
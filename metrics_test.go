@@ -0,0 +1,101 @@
+package bonk
+
+import (
+	"math"
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+)
+
+func TestCalculatePoolMetrics(t *testing.T) {
+	withStatus := func(pool raydium_launchpad.PoolState, status raydium_launchpad.PoolStatus) *raydium_launchpad.PoolState {
+		pool.Status = uint8(status)
+		pool.Supply = 1_000_000_000_000_000
+		pool.BaseDecimals = 6
+		pool.QuoteDecimals = 9
+		return &pool
+	}
+	constant := *bonkPool(200_000_000_000_000, 6_872_846_056)
+	fixed := raydium_launchpad.PoolState{
+		TotalBaseSell:         500_000_000_000_000,
+		VirtualBase:           1_000_000_000,
+		VirtualQuote:          37,
+		RealBase:              100_000_000_000_000,
+		RealQuote:             3_700_000,
+		TotalQuoteFundRaising: 18_500_000,
+	}
+	// real_quote = ceil(a * real_base^2 / 2)
+	linear := raydium_launchpad.PoolState{
+		TotalBaseSell:         793_100_000_000_000,
+		VirtualBase:           4985,
+		RealBase:              300_000_000_000_000,
+		RealQuote:             12_160_682_617_141,
+		TotalQuoteFundRaising: 84_990_687_877_513,
+	}
+	// 剩余的 base 几乎等于虚拟储备, 买完需要的 quote 超出 u64
+	overflow := raydium_launchpad.PoolState{
+		TotalBaseSell:         1_000_000,
+		VirtualBase:           1_000_001,
+		VirtualQuote:          1 << 62,
+		TotalQuoteFundRaising: 1,
+	}
+
+	// 期望值按曲线公式独立计算
+	type prices struct{ spot, marketCap float64 }
+	var (
+		constantPrice = prices{4.22366752711804e-08, 42.2366752711804}
+		fixedPrice    = prices{3.7e-11, 0.037}
+		linearPrice   = prices{8.10712174476036e-05, 81071.21744760359}
+	)
+	tests := []struct {
+		name      string
+		pool      *raydium_launchpad.PoolState
+		curveType CurveType
+		funding   float64
+		baseLeft  uint64
+		quoteLeft uint64
+		price     prices
+	}{
+		{"constant_fund", withStatus(constant, raydium_launchpad.PoolStatus_Fund), CurveType_Constant,
+			8.085701242352942, 593_100_000_000_000, 78_127_153_944, constantPrice},
+		{"constant_migrate", withStatus(constant, raydium_launchpad.PoolStatus_Migrate), CurveType_Constant, 100, 0, 0, constantPrice},
+		{"constant_trade", withStatus(constant, raydium_launchpad.PoolStatus_Trade), CurveType_Constant, 100, 0, 0, constantPrice},
+		{"fixed_fund", withStatus(fixed, raydium_launchpad.PoolStatus_Fund), CurveType_Fixed,
+			20, 400_000_000_000_000, 14_800_000, fixedPrice},
+		{"fixed_migrate", withStatus(fixed, raydium_launchpad.PoolStatus_Migrate), CurveType_Fixed, 100, 0, 0, fixedPrice},
+		{"fixed_trade", withStatus(fixed, raydium_launchpad.PoolStatus_Trade), CurveType_Fixed, 100, 0, 0, fixedPrice},
+		{"linear_fund", withStatus(linear, raydium_launchpad.PoolStatus_Fund), CurveType_Linear,
+			14.308252963744392, 493_100_000_000_000, 72_830_005_260_372, linearPrice},
+		{"linear_migrate", withStatus(linear, raydium_launchpad.PoolStatus_Migrate), CurveType_Linear, 100, 0, 0, linearPrice},
+		{"linear_trade", withStatus(linear, raydium_launchpad.PoolStatus_Trade), CurveType_Linear, 100, 0, 0, linearPrice},
+		{"quote_overflow", withStatus(overflow, raydium_launchpad.PoolStatus_Fund), CurveType_Constant,
+			0, 1_000_000, math.MaxUint64, prices{4.611681406745981e+09, 4.611681406745981e+18}},
+	}
+	closeTo := func(got, want float64) bool {
+		return got == want || math.Abs(got-want) <= 1e-12*math.Abs(want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CalculatePoolMetrics(tt.pool, tt.curveType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !closeTo(got.FundingPercent, tt.funding) {
+				t.Errorf("FundingPercent = %v, want %v", got.FundingPercent, tt.funding)
+			}
+			if got.BaseRemaining != tt.baseLeft || got.QuoteRemaining != tt.quoteLeft {
+				t.Errorf("remaining = %d/%d, want %d/%d", got.BaseRemaining, got.QuoteRemaining, tt.baseLeft, tt.quoteLeft)
+			}
+			if !closeTo(got.SpotPrice, tt.price.spot) || !closeTo(got.MarketCap, tt.price.marketCap) {
+				t.Errorf("price = %v/%v, want %v/%v", got.SpotPrice, got.MarketCap, tt.price.spot, tt.price.marketCap)
+			}
+		})
+	}
+
+	if _, err := CalculatePoolMetrics(nil, CurveType_Constant); err == nil {
+		t.Error("CalculatePoolMetrics(nil) error = nil")
+	}
+	if _, err := CalculatePoolMetrics(withStatus(constant, raydium_launchpad.PoolStatus_Fund), CurveType(3)); err == nil {
+		t.Error("CalculatePoolMetrics(unknown curve) error = nil")
+	}
+}
@@ -0,0 +1,206 @@
+package bonk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/go-log"
+)
+
+// Migration 一次迁移的结果, 通过 PoolState 关联到原来的 launchpad 池子
+type Migration struct {
+	Signature   string           `json:"signature"`
+	Slot        uint64           `json:"slot"`
+	Index       int              `json:"index"`       // 外层指令索引
+	InnerIndex  int              `json:"inner_index"` // inner instruction 索引, 非CPI时为 -1
+	MigrateType MigrateType      `json:"migrate_type"`
+	PoolState   solana.PublicKey `json:"pool_state"` // 原 launchpad 池子
+	BaseMint    solana.PublicKey `json:"base_mint"`
+	QuoteMint   solana.PublicKey `json:"quote_mint"`
+
+	Pool           solana.PublicKey `json:"pool"` // 迁移后的 AMM v4 或 CPSWAP 池子
+	AmmProgram     solana.PublicKey `json:"amm_program"`
+	LpMint         solana.PublicKey `json:"lp_mint"`
+	PoolBaseVault  solana.PublicKey `json:"pool_base_vault"`
+	PoolQuoteVault solana.PublicKey `json:"pool_quote_vault"`
+	PoolLpToken    solana.PublicKey `json:"pool_lp_token"` // launchpad 接收 LP 的账户
+
+	// 锁仓 LP 的账户, 只有 CPSWAP 迁移使用
+	LockProgram   solana.PublicKey   `json:"lock_program"`
+	LockAuthority solana.PublicKey   `json:"lock_authority"`
+	LockLpVault   solana.PublicKey   `json:"lock_lp_vault"`
+	NftMints      []solana.PublicKey `json:"nft_mints"` // 锁仓产生的 NFT
+
+	BaseAmount     uint64 `json:"base_amount"`      // 转入新池子的 base 数量
+	QuoteAmount    uint64 `json:"quote_amount"`     // 转入新池子的 quote 数量
+	LpAmount       uint64 `json:"lp_amount"`        // 交易结束后各账户增加的 LP 数量, 同一交易中销毁的部分不计算
+	LockedLpAmount uint64 `json:"locked_lp_amount"` // 锁仓的 LP 数量
+
+	Instruction LaunchpadInstruction `json:"instruction"` // *MigrateToAmmInstruction / *MigrateToCpswapInstruction
+}
+
+// tokenBalance 交易前后代币账户的余额
+type tokenBalance struct {
+	mint      solana.PublicKey
+	decimals  uint8
+	pre, post uint64
+}
+
+func (b *tokenBalance) increase() uint64 {
+	if b == nil || b.post < b.pre {
+		return 0
+	}
+	return b.post - b.pre
+}
+
+// tokenBalances 按账户汇总交易前后的代币余额, 交易前不存在的账户余额为0
+func tokenBalances(transaction *solana.Transaction, meta *rpc.TransactionMeta) map[solana.PublicKey]*tokenBalance {
+	balances := make(map[solana.PublicKey]*tokenBalance)
+	collect := func(items []rpc.TokenBalance, post bool) {
+		for _, item := range items {
+			if int(item.AccountIndex) >= len(transaction.Message.AccountKeys) || item.UiTokenAmount == nil {
+				continue
+			}
+			amount, err := strconv.ParseUint(item.UiTokenAmount.Amount, 10, 64)
+			if err != nil {
+				continue
+			}
+			key := transaction.Message.AccountKeys[item.AccountIndex]
+			balance, ok := balances[key]
+			if !ok {
+				balance = &tokenBalance{mint: item.Mint, decimals: item.UiTokenAmount.Decimals}
+				balances[key] = balance
+			}
+			if post {
+				balance.post = amount
+			} else {
+				balance.pre = amount
+			}
+		}
+	}
+	collect(meta.PreTokenBalances, false)
+	collect(meta.PostTokenBalances, true)
+	return balances
+}
+
+// newMigration 根据迁移指令和余额变化生成迁移结果, 不是迁移指令时返回 nil
+func newMigration(ix LaunchpadInstruction, balances map[solana.PublicKey]*tokenBalance) *Migration {
+	var migration *Migration
+	switch ix := ix.(type) {
+	case *MigrateToAmmInstruction:
+		accounts := ix.Accounts
+		migration = &Migration{
+			MigrateType:    MigrateType_Amm,
+			PoolState:      accounts.PoolState,
+			BaseMint:       accounts.BaseMint,
+			QuoteMint:      accounts.QuoteMint,
+			Pool:           accounts.AmmPool,
+			AmmProgram:     accounts.AmmProgram,
+			LpMint:         accounts.AmmLpMint,
+			PoolBaseVault:  accounts.AmmBaseVault,
+			PoolQuoteVault: accounts.AmmQuoteVault,
+			PoolLpToken:    accounts.PoolLpToken,
+		}
+	case *MigrateToCpswapInstruction:
+		accounts := ix.Accounts
+		migration = &Migration{
+			MigrateType:    MigrateType_Cpswap,
+			PoolState:      accounts.PoolState,
+			BaseMint:       accounts.BaseMint,
+			QuoteMint:      accounts.QuoteMint,
+			Pool:           accounts.CpswapPool,
+			AmmProgram:     accounts.CpswapProgram,
+			LpMint:         accounts.CpswapLpMint,
+			PoolBaseVault:  accounts.CpswapBaseVault,
+			PoolQuoteVault: accounts.CpswapQuoteVault,
+			PoolLpToken:    accounts.PoolLpToken,
+			LockProgram:    accounts.LockProgram,
+			LockAuthority:  accounts.LockAuthority,
+			LockLpVault:    accounts.LockLpVault,
+		}
+		migration.LockedLpAmount = balances[accounts.LockLpVault].increase()
+	default:
+		return nil
+	}
+	migration.Instruction = ix
+	migration.BaseAmount = balances[migration.PoolBaseVault].increase()
+	migration.QuoteAmount = balances[migration.PoolQuoteVault].increase()
+
+	for _, balance := range balances {
+		switch {
+		case balance.mint.Equals(migration.LpMint):
+			migration.LpAmount += balance.increase()
+		case migration.MigrateType == MigrateType_Cpswap && balance.decimals == 0 && balance.pre == 0 && balance.post == 1 &&
+			!balance.mint.Equals(migration.BaseMint) && !balance.mint.Equals(migration.QuoteMint):
+			// 锁仓时 lock 程序会铸造代表 LP 权益的 NFT
+			migration.NftMints = append(migration.NftMints, balance.mint)
+		}
+	}
+	return migration
+}
+
+// ExtractMigrations 从完整交易中提取全部迁移, 包括通过CPI调用的迁移指令
+//
+// v0交易会使用meta中的 LoadedAddresses 解析地址查找表
+func ExtractMigrations(signature string, transaction *rpc.GetTransactionResult) ([]*Migration, error) {
+	if transaction == nil || transaction.Meta == nil {
+		return nil, errors.New("交易信息为空")
+	}
+	transactionInfo, err := transaction.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("解析交易失败: %w", err)
+	}
+	if err := ResolveLoadedAddresses(transactionInfo, transaction.Meta); err != nil {
+		return nil, err
+	}
+	return extractMigrations(signature, transaction, transactionInfo), nil
+}
+
+func extractMigrations(signature string, transaction *rpc.GetTransactionResult, transactionInfo *solana.Transaction) []*Migration {
+	if transaction.Meta.Err != nil {
+		return nil
+	}
+
+	var result []*Migration
+	balances := tokenBalances(transactionInfo, transaction.Meta)
+	for _, ref := range flattenInstructions(transactionInfo, transaction.Meta) {
+		// 跳过 emit_cpi! 产生的事件自调用
+		if len(ref.Instruction.Data) >= 8 && bytes.Equal(ref.Instruction.Data[:8], EventIxTag[:]) {
+			continue
+		}
+		ix, err := DecodeInstruction(ref.Instruction, transactionInfo)
+		if err != nil {
+			if !errors.Is(err, ErrNotLaunchpadInstruction) {
+				log.Error(fmt.Sprintf("解析指令失败! 签名: %s, 指令索引: %d/%d |", signature, ref.Index, ref.InnerIndex), err)
+			}
+			continue
+		}
+		migration := newMigration(ix, balances)
+		if migration == nil {
+			continue
+		}
+		migration.Signature = signature
+		migration.Slot = transaction.Slot
+		migration.Index = ref.Index
+		migration.InnerIndex = ref.InnerIndex
+		result = append(result, migration)
+	}
+	return result
+}
+
+// ProcessTransactionMigrations 获取交易并提取其中的全部迁移
+func (p *PoolMonit) ProcessTransactionMigrations(signature solana.Signature) ([]*Migration, error) {
+	transaction, transactionInfo, err := p.getTransaction(p.ctx, signature)
+	if err != nil {
+		return nil, err
+	}
+	migrations := extractMigrations(signature.String(), transaction, transactionInfo)
+	if len(migrations) == 0 {
+		return nil, errors.New("交易中没有迁移指令")
+	}
+	return migrations, nil
+}
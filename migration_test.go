@@ -0,0 +1,262 @@
+package bonk
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// buildLaunchpadInstruction 调用生成的 builder, 每个账户使用不同的地址, 返回指令和按 IDL 名称索引的账户
+func buildLaunchpadInstruction(t *testing.T, builder any, params []any, data []byte) (solana.Instruction, map[string]solana.PublicKey) {
+	t.Helper()
+	fn := reflect.ValueOf(builder)
+	name := runtime.FuncForPC(fn.Pointer()).Name()
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	keys := make(map[string]solana.PublicKey)
+	args := make([]reflect.Value, 0, fn.Type().NumIn())
+	for _, param := range params {
+		args = append(args, reflect.ValueOf(param))
+	}
+	for _, account := range builderAccounts(t)[name] {
+		keys[account] = solana.NewWallet().PublicKey()
+		args = append(args, reflect.ValueOf(keys[account]))
+	}
+	out := fn.Call(args)
+	if err, _ := out[1].Interface().(error); err != nil {
+		t.Fatal(err)
+	}
+	instruction := out[0].Interface().(solana.Instruction)
+	if data != nil {
+		// 没有参数的指令 builder 不写入 discriminator
+		instruction = solana.NewInstruction(instruction.ProgramID(), instruction.Accounts(), data)
+	}
+	return instruction, keys
+}
+
+// migrationBalance 交易前后的代币余额, pre 为负数表示交易前账户不存在
+type migrationBalance struct {
+	account   solana.PublicKey
+	mint      solana.PublicKey
+	decimals  uint8
+	pre, post int64
+}
+
+// migrationTransaction 使用随机的 payer 构建交易
+func migrationTransaction(t *testing.T, instructions []solana.Instruction) *solana.Transaction {
+	t.Helper()
+	payer := solana.NewWallet().PublicKey()
+	transaction, err := solana.NewTransaction(instructions, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transaction
+}
+
+// tokenBalanceMeta 按账户在交易中的索引生成 pre/post token balances
+func tokenBalanceMeta(t *testing.T, transaction *solana.Transaction, balances []migrationBalance) *rpc.TransactionMeta {
+	t.Helper()
+	meta := &rpc.TransactionMeta{}
+	for _, balance := range balances {
+		index := -1
+		for i, key := range transaction.Message.AccountKeys {
+			if key.Equals(balance.account) {
+				index = i
+			}
+		}
+		if index < 0 {
+			t.Fatalf("account %s not in transaction", balance.account)
+		}
+		item := func(amount int64) rpc.TokenBalance {
+			return rpc.TokenBalance{
+				AccountIndex:  uint16(index),
+				Mint:          balance.mint,
+				UiTokenAmount: &rpc.UiTokenAmount{Amount: strconv.FormatInt(amount, 10), Decimals: balance.decimals},
+			}
+		}
+		if balance.pre >= 0 {
+			meta.PreTokenBalances = append(meta.PreTokenBalances, item(balance.pre))
+		}
+		meta.PostTokenBalances = append(meta.PostTokenBalances, item(balance.post))
+	}
+	return meta
+}
+
+// holder 交易中其他指令引用的代币账户, 用于放入余额变化
+func holder(accounts ...solana.PublicKey) solana.Instruction {
+	metas := make(solana.AccountMetaSlice, len(accounts))
+	for i, account := range accounts {
+		metas[i] = solana.Meta(account).WRITE()
+	}
+	return solana.NewInstruction(solana.TokenProgramID, metas, []byte{3})
+}
+
+func TestExtractMigrationsCpswap(t *testing.T) {
+	migrate, keys := buildLaunchpadInstruction(t, raydium_launchpad.NewMigrateToCpswapInstruction, nil,
+		raydium_launchpad.Instruction_MigrateToCpswap[:])
+	var (
+		nftMint      = solana.NewWallet().PublicKey()
+		nftAccount   = solana.NewWallet().PublicKey()
+		dustAccount  = solana.NewWallet().PublicKey()
+		otherAccount = solana.NewWallet().PublicKey()
+	)
+	instructions := []solana.Instruction{migrate, holder(nftAccount, dustAccount, otherAccount)}
+	transaction := migrationTransaction(t, instructions)
+	meta := tokenBalanceMeta(t, transaction, []migrationBalance{
+		{keys["cpswap_base_vault"], keys["base_mint"], 6, -1, 200_000_000},
+		{keys["cpswap_quote_vault"], keys["quote_mint"], 9, -1, 85_000_000_000},
+		{keys["base_vault"], keys["base_mint"], 6, 200_000_000, 0},
+		// LP 先铸造到 pool_lp_token, 其中大部分转入锁仓账户
+		{keys["pool_lp_token"], keys["cpswap_lp_mint"], 9, -1, 10},
+		{keys["lock_lp_vault"], keys["cpswap_lp_mint"], 9, -1, 90},
+		// lock 程序铸造的 NFT: 精度 0, 余额从 0 变为 1
+		{nftAccount, nftMint, 0, -1, 1},
+		// 精度 0 的 base 代币和余额不是 1 的代币不是 NFT
+		{dustAccount, keys["base_mint"], 0, 0, 1},
+		{otherAccount, solana.NewWallet().PublicKey(), 0, -1, 2},
+	})
+
+	migrations := extractMigrations("sig", &rpc.GetTransactionResult{Slot: 9, Meta: meta}, transaction)
+	if len(migrations) != 1 {
+		t.Fatalf("%d migrations, want 1", len(migrations))
+	}
+	got := migrations[0]
+	want := &Migration{
+		Signature:      "sig",
+		Slot:           9,
+		Index:          0,
+		InnerIndex:     -1,
+		MigrateType:    MigrateType_Cpswap,
+		PoolState:      keys["pool_state"],
+		BaseMint:       keys["base_mint"],
+		QuoteMint:      keys["quote_mint"],
+		Pool:           keys["cpswap_pool"],
+		AmmProgram:     keys["cpswap_program"],
+		LpMint:         keys["cpswap_lp_mint"],
+		PoolBaseVault:  keys["cpswap_base_vault"],
+		PoolQuoteVault: keys["cpswap_quote_vault"],
+		PoolLpToken:    keys["pool_lp_token"],
+		LockProgram:    keys["lock_program"],
+		LockAuthority:  keys["lock_authority"],
+		LockLpVault:    keys["lock_lp_vault"],
+		NftMints:       []solana.PublicKey{nftMint},
+		BaseAmount:     200_000_000,
+		QuoteAmount:    85_000_000_000,
+		LpAmount:       100,
+		LockedLpAmount: 90,
+		Instruction:    got.Instruction,
+	}
+	if _, ok := got.Instruction.(*MigrateToCpswapInstruction); !ok {
+		t.Errorf("Instruction = %T", got.Instruction)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestExtractMigrationsAmm(t *testing.T) {
+	migrate, keys := buildLaunchpadInstruction(t, raydium_launchpad.NewMigrateToAmmInstruction,
+		[]any{uint64(1), uint64(2), uint8(3)}, nil)
+	eventAuthority, _, err := FindEventAuthorityPDA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nftAccount := solana.NewWallet().PublicKey()
+
+	// 聚合器路由通过CPI调用迁移, 外层指令需要引用迁移的全部账户
+	router := solana.NewWallet().PublicKey()
+	accounts := append(solana.AccountMetaSlice{solana.Meta(raydium_launchpad.ProgramID), solana.Meta(eventAuthority)}, migrate.Accounts()...)
+	outer := solana.NewInstruction(router, append(accounts, solana.Meta(nftAccount).WRITE()), []byte{1})
+	transaction := migrationTransaction(t, []solana.Instruction{outer})
+
+	compile := func(instruction solana.Instruction) solana.CompiledInstruction {
+		index := func(key solana.PublicKey) uint16 {
+			for i, account := range transaction.Message.AccountKeys {
+				if account.Equals(key) {
+					return uint16(i)
+				}
+			}
+			t.Fatalf("account %s not in transaction", key)
+			return 0
+		}
+		data, err := instruction.Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		compiled := solana.CompiledInstruction{ProgramIDIndex: index(instruction.ProgramID()), Data: data}
+		for _, meta := range instruction.Accounts() {
+			compiled.Accounts = append(compiled.Accounts, index(meta.PublicKey))
+		}
+		return compiled
+	}
+	event := solana.NewInstruction(raydium_launchpad.ProgramID, solana.AccountMetaSlice{solana.Meta(eventAuthority)},
+		append(EventIxTag[:], raydium_launchpad.Event_TradeEvent[:]...))
+
+	meta := tokenBalanceMeta(t, transaction, []migrationBalance{
+		{keys["amm_base_vault"], keys["base_mint"], 6, -1, 200_000_000},
+		{keys["amm_quote_vault"], keys["quote_mint"], 9, -1, 85_000_000_000},
+		{keys["pool_lp_token"], keys["amm_lp_mint"], 9, -1, 100},
+		// AMM 迁移不锁仓, 不识别 NFT
+		{nftAccount, solana.NewWallet().PublicKey(), 0, -1, 1},
+	})
+	meta.InnerInstructions = []rpc.InnerInstruction{
+		{Index: 0, Instructions: []solana.CompiledInstruction{compile(event), compile(migrate)}},
+	}
+	router58 := "Program " + router.String()
+	launchpad := "Program " + raydium_launchpad.ProgramID.String()
+	meta.LogMessages = []string{
+		router58 + " invoke [1]",
+		launchpad + " invoke [2]",
+		launchpad + " success",
+		launchpad + " invoke [2]",
+		"Program log: Instruction: MigrateToAmm",
+		launchpad + " success",
+		router58 + " success",
+	}
+
+	migrations := extractMigrations("sig", &rpc.GetTransactionResult{Slot: 9, Meta: meta}, transaction)
+	if len(migrations) != 1 {
+		t.Fatalf("%d migrations, want 1", len(migrations))
+	}
+	got := migrations[0]
+	instruction, ok := got.Instruction.(*MigrateToAmmInstruction)
+	if !ok || instruction.BaseLotSize != 1 || instruction.QuoteLotSize != 2 || instruction.MarketVaultSignerNonce != 3 {
+		t.Errorf("Instruction = %+v", got.Instruction)
+	}
+	want := &Migration{
+		Signature:      "sig",
+		Slot:           9,
+		Index:          0,
+		InnerIndex:     1,
+		MigrateType:    MigrateType_Amm,
+		PoolState:      keys["pool_state"],
+		BaseMint:       keys["base_mint"],
+		QuoteMint:      keys["quote_mint"],
+		Pool:           keys["amm_pool"],
+		AmmProgram:     keys["amm_program"],
+		LpMint:         keys["amm_lp_mint"],
+		PoolBaseVault:  keys["amm_base_vault"],
+		PoolQuoteVault: keys["amm_quote_vault"],
+		PoolLpToken:    keys["pool_lp_token"],
+		BaseAmount:     200_000_000,
+		QuoteAmount:    85_000_000_000,
+		LpAmount:       100,
+		Instruction:    got.Instruction,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	// 失败的交易没有迁移
+	meta.Err = map[string]any{"InstructionError": []any{0, "InvalidAccountData"}}
+	if migrations := extractMigrations("sig", &rpc.GetTransactionResult{Meta: meta}, transaction); len(migrations) != 0 {
+		t.Errorf("failed transaction migrations = %v", migrations)
+	}
+}
@@ -2,6 +2,8 @@ package bonk
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -13,109 +15,447 @@ import (
 	"github.com/go-enols/gosolana/ws"
 )
 
+// LogApusic 不返回错误的日志中间件, 实现了 LogHandler
 type LogApusic func(*ws.LogResult)
 
 type Client struct {
 	RpcClient *rpc.Client // http rpc
-	WsClient  *ws.Client  // websocket
+	RPC       *RPC        // 共享的 RPC, 设置后补齐、查询等 http 请求经过它限流和重试, 为 nil 时使用 RpcClient
+	WsClient  *ws.Client  // 第一个端点的 websocket
 
-	LogApusic []LogApusic // 异步中间件，在获取到日志后，异步处理
+	// Deprecated: 仅为兼容保留, UseLog 添加的中间件会追加到这里, 直接修改不会影响分发; 使用 UseLog 返回的 LogHandle 移除中间件
+	LogApusic []LogApusic
+
+	handlers      []*logHandler         // 异步中间件，在获取到日志后，异步处理
+	handlerSeq    int                   // 中间件序号, 用于默认名称
+	errorHandlers []HandlerErrorHandler // 中间件错误回调
 	// 价格中间件，在获取到价格之后处理
 
-	LogQuque chan *ws.LogResult // 日志队列
-	lock     sync.RWMutex       // 读写锁，确保读写安全
-	ctx      context.Context
-	cancel   context.CancelFunc
-	option   []gosolana.Option
+	LogQuque    chan *ws.LogResult // 日志队列
+	QueuePolicy QueuePolicy        // 日志队列写满时的处理方式, 默认阻塞
+	Workers     int                // 同时运行的中间件数量上限, 0 表示不限制, 需要在收到第一条日志前设置
+	dispatch    dispatcher
+
+	ReconnectPolicy ReconnectPolicy    // 重连策略, 每个端点独立计算, 默认使用 DefaultReconnectPolicy
+	stateHandlers   []ConnStateHandler // 连接状态变化回调
+
+	Backfill      bool             // 重连后是否补齐断线期间遗漏的交易, 默认 true
+	BackfillLimit int              // 单次最多补齐的交易数量, 0 表示不限制
+	lastSignature solana.Signature // 最后一笔收到的交易
+	lastSlot      uint64
+
+	Dedup *SignatureDedup // 签名去重, 保证每笔交易只分发一次, 为空时不去重
+
+	endpoints []*endpoint // websocket 订阅端点, 同时订阅, 最先到达的通知生效
+
+	closed        bool
+	running       sync.WaitGroup     // 正在运行的 Start
+	closeCtx      context.Context    // Close 时结束, 停止全部订阅
+	closeCancel   context.CancelFunc //
+	handlerCtx    context.Context    // 传递给中间件, Close 完成或超时时结束
+	handlerCancel context.CancelFunc //
+	processDone   chan struct{}      // 日志队列分发完成
+
+	lock sync.RWMutex // 读写锁，确保读写安全
+	ctx  context.Context
 }
 
+var ErrClientClosed = errors.New("Client 已关闭")
+
+// NewClient 创建监听客户端
+//
+// 每个 option 对应一个 websocket 端点, 多个端点会同时订阅并以最先到达的通知为准, http rpc 使用第一个 option
 func NewClient(ctx context.Context, option ...gosolana.Option) *Client {
-	_ctx, cancel := context.WithCancel(ctx)
-	opt := gosolana.NewDefaultOption(_ctx, option...)
+	if len(option) == 0 {
+		option = []gosolana.Option{{}}
+	}
 
 	c := &Client{
-		RpcClient: opt.RpcClient,
-		WsClient:  opt.WsClient,
-		LogQuque:  make(chan *ws.LogResult, 1000),
-		lock:      sync.RWMutex{},
-		ctx:       ctx,
-		cancel:    cancel,
-		option:    option,
-	}
-	go c.logProcess(ctx)
+		LogQuque:        make(chan *ws.LogResult, 1000),
+		Workers:         64,
+		ReconnectPolicy: DefaultReconnectPolicy,
+		Backfill:        true,
+		BackfillLimit:   5000,
+		Dedup:           NewSignatureDedup(100_000, 10*time.Minute),
+		processDone:     make(chan struct{}),
+		lock:            sync.RWMutex{},
+		ctx:             ctx,
+	}
+	c.closeCtx, c.closeCancel = context.WithCancel(ctx)
+	c.handlerCtx, c.handlerCancel = context.WithCancel(ctx)
+	for i, o := range option {
+		_ctx, cancel := context.WithCancel(ctx)
+		ep := &endpoint{
+			url:    o.WsUrl,
+			proxy:  o.WsProxy,
+			client: o.WsClient,
+			owns:   o.WsClient == nil,
+			cancel: cancel,
+		}
+		if i == 0 {
+			// 第一个端点保持原有行为, 创建时建立连接, 拨号失败时 panic;
+			// 连接由 dialWs 建立, 不使用 NewDefaultOption 拨号, 避免与 ws.Client 内部的重连共用
+			if ep.url == "" {
+				ep.url = rpc.DevNet_WS
+			}
+			if ep.owns {
+				client, err := dialWs(ctx, ep.url, ep.proxy)
+				if err != nil && ctx.Err() == nil {
+					panic(err)
+				}
+				// ctx 已经结束时 client 为空, NewDefaultOption 不会拨号, 由 Start 返回 ctx 的错误
+				ep.client = client
+				o.WsClient = client
+			}
+			o.WsUrl = ep.url
+			opt := gosolana.NewDefaultOption(_ctx, o)
+			c.RpcClient = opt.RpcClient
+			c.WsClient = ep.client
+		}
+		c.endpoints = append(c.endpoints, ep)
+	}
+	go c.logProcess(c.handlerCtx)
 	return c
 }
 
 // 重新链接到客户端
+//
+// 关闭全部端点旧的websocket连接后重新拨号, 拨号失败时记录日志而不是 panic, Start 运行中会按 ReconnectPolicy 继续重连
 func (c *Client) Reconnect() {
-	c.cancel()
+	for _, ep := range c.endpoints {
+		if err := c.reconnect(ep); err != nil {
+			log.Println("WebSocket 重连失败 | ", err)
+		}
+	}
+}
+
+// reconnect 关闭端点旧的websocket连接后重新拨号
+func (c *Client) reconnect(ep *endpoint) error {
+	c.lock.Lock()
+	ep.cancel()
+	if ep.owns {
+		closeWsClient(ep.client)
+	}
+	ep.client = nil
 	_ctx, cancel := context.WithCancel(c.ctx)
-	opt := gosolana.NewDefaultOption(_ctx, c.option...)
-	c.RpcClient = opt.RpcClient
-	c.WsClient = opt.WsClient
-	c.cancel = cancel
-	log.Println("WebSocket 连接成功")
+	ep.cancel = cancel
+	c.lock.Unlock()
+
+	wsClient, err := dialWs(_ctx, ep.url, ep.proxy)
+	if err != nil {
+		return fmt.Errorf("连接 %s 失败: %w", ep.url, err)
+	}
+
+	c.lock.Lock()
+	ep.client = wsClient
+	ep.owns = true
+	if ep == c.endpoints[0] {
+		c.WsClient = wsClient
+	}
+	c.lock.Unlock()
+	ep.reconnects.Add(1)
+	log.Println("WebSocket 连接成功 | ", ep.url)
+	return nil
 }
 
 // 启动监听, 如果需要取消请直接结束ctx
+//
+// 每个端点独立订阅和重连, 连接断开后按 ReconnectPolicy 指数退避重连,
+// 全部端点连续失败次数都超出 MaxFailures 时返回 ErrReconnectExhausted;
+// ctx 结束只停止订阅, 已经入队的日志会继续分发, 需要等待分发完成时调用 Close
 func (c *Client) Start(ctx context.Context, pubKey solana.PublicKey, commit rpc.CommitmentType) (*ws.LogSubscription, error) {
+	if len(c.endpoints) > 1 && c.Dedup == nil {
+		// 多个端点依赖去重选出最先到达的通知
+		c.Dedup = NewSignatureDedup(100_000, 10*time.Minute)
+	}
+	return nil, c.run(ctx, func(ctx context.Context, ep *endpoint) (time.Time, error) {
+		return c.monit(ctx, ep, pubKey, commit)
+	})
+}
+
+// run 在每个端点上执行 subscribe 并维持连接, 直到 ctx 结束、Close 或全部端点重连失败
+func (c *Client) run(ctx context.Context, subscribe subscribeFunc) error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return ErrClientClosed
+	}
+	c.running.Add(1)
+	c.lock.Unlock()
+	defer c.running.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.closeCtx, cancel)
+	defer stop()
+
+	results := make(chan error, len(c.endpoints))
+	for _, ep := range c.endpoints {
+		go func(ep *endpoint) {
+			results <- c.supervise(ctx, ep, subscribe)
+		}(ep)
+	}
+	var errs []error
+	for range c.endpoints {
+		errs = append(errs, <-results)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// Close 停止全部订阅并等待已经入队的日志分发完成
+//
+// 关闭日志队列后等待队列中的日志以及正在运行的中间件处理完成, 之后中间件的 ctx 结束;
+// ctx 结束时不再等待并返回错误, 关闭后 Start 返回 ErrClientClosed, 可以重复调用
+func (c *Client) Close(ctx context.Context) error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.lock.Unlock()
+
+	c.closeCancel()
+	err := waitContext(ctx, c.running.Wait)
+	if err == nil {
+		// 不再有写入, 关闭队列后分发协程会处理完剩余的日志再退出
+		close(c.LogQuque)
+		err = waitContext(ctx, func() { <-c.processDone })
+	}
+	if err == nil {
+		err = waitContext(ctx, c.dispatch.wg.Wait)
+	}
+	c.handlerCancel()
+
+	c.lock.Lock()
+	for _, ep := range c.endpoints {
+		ep.cancel()
+		if ep.owns {
+			closeWsClient(ep.client)
+		}
+		ep.client = nil
+	}
+	c.lock.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("关闭超时: %w", err)
+	}
+	return nil
+}
+
+// waitContext 等待 wait 返回, ctx 结束时提前返回错误
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// subscribeFunc 在端点上订阅并处理通知直到出错, 返回订阅成功的时间(订阅失败时为零值)以及导致退出的错误
+type subscribeFunc func(ctx context.Context, ep *endpoint) (time.Time, error)
+
+// supervise 维持单个端点的订阅, 断开后按 ReconnectPolicy 重连并重新执行 subscribe, 返回 ctx 的错误或 ErrReconnectExhausted
+func (c *Client) supervise(ctx context.Context, ep *endpoint, subscribe subscribeFunc) error {
+	policy := c.ReconnectPolicy.withDefaults()
+	failures := 0
 	for {
+		if ctx.Err() != nil {
+			c.setState(ep, ConnStateClosed, failures, 0, ctx.Err())
+			return ctx.Err()
+		}
+
+		var err error
+		if failures > 0 || c.getWsClient(ep) == nil {
+			c.setState(ep, ConnStateConnecting, failures, 0, nil)
+			err = c.reconnect(ep)
+		}
+		if err == nil {
+			var connectedAt time.Time
+			connectedAt, err = subscribe(ctx, ep)
+			if !connectedAt.IsZero() && time.Since(connectedAt) >= policy.ResetAfter {
+				failures = 0
+			}
+		}
+		if ctx.Err() != nil {
+			c.setState(ep, ConnStateClosed, failures, 0, ctx.Err())
+			return ctx.Err()
+		}
+
+		failures++
+		if policy.exhausted(failures) {
+			c.setState(ep, ConnStateFailed, failures, 0, err)
+			return fmt.Errorf("%s %w(%d): %v", ep.url, ErrReconnectExhausted, failures, err)
+		}
+		delay := policy.Delay(failures)
+		c.setState(ep, ConnStateDisconnected, failures, delay, err)
+		log.Printf("WebSocket链接异常, %s 后重连 | %s | %v", delay, ep.url, err)
+
+		timer := time.NewTimer(delay)
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			c.monit(ctx, pubKey, commit)
-			c.Reconnect()
+			timer.Stop()
+		case <-timer.C:
 		}
 	}
 }
 
-// 添加一个日志中间件，在获取到日志后，异步处理
-func (c *Client) UseLog(apusic LogApusic) {
-	// 加锁确保写入安全
+// OnStateChange 添加一个连接状态变化回调, 每个端点的状态变化都会触发
+func (c *Client) OnStateChange(handler ConnStateHandler) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	c.LogApusic = append(c.LogApusic, apusic)
+	c.stateHandlers = append(c.stateHandlers, handler)
+}
+
+// State 当前的连接状态, 多个端点时任意端点订阅成功即为 Connected, 全部失败才为 Failed
+func (c *Client) State() ConnState {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	state := ConnStateFailed
+	for _, ep := range c.endpoints {
+		if statePriority(ep.state) > statePriority(state) {
+			state = ep.state
+		}
+	}
+	return state
+}
+
+// EndpointStats 获取每个端点的连接状态、最先到达率和延迟统计
+func (c *Client) EndpointStats() []EndpointStats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var totalWins uint64
+	for _, ep := range c.endpoints {
+		totalWins += ep.wins.Load()
+	}
+	result := make([]EndpointStats, 0, len(c.endpoints))
+	for _, ep := range c.endpoints {
+		stats := EndpointStats{
+			Endpoint:      ep.url,
+			State:         ep.state,
+			Notifications: ep.notifications.Load(),
+			Wins:          ep.wins.Load(),
+			MaxDelay:      time.Duration(ep.delayMax.Load()),
+			Reconnects:    ep.reconnects.Load(),
+		}
+		if totalWins > 0 {
+			stats.WinRate = float64(stats.Wins) / float64(totalWins)
+		}
+		if stats.Notifications > 0 {
+			stats.AvgDelay = time.Duration(ep.delaySum.Load() / int64(stats.Notifications))
+		}
+		result = append(result, stats)
+	}
+	return result
+}
+
+func (c *Client) setState(ep *endpoint, state ConnState, attempt int, delay time.Duration, err error) {
+	c.lock.Lock()
+	event := ConnEvent{
+		Endpoint: ep.url,
+		From:     ep.state,
+		State:    state,
+		Attempt:  attempt,
+		Delay:    delay,
+		Err:      err,
+		Time:     time.Now(),
+	}
+	ep.state = state
+	handlers := append([]ConnStateHandler(nil), c.stateHandlers...)
+	c.lock.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (c *Client) getWsClient(ep *endpoint) *ws.Client {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return ep.client
 }
 
 // 订阅日志，传输给管道
-func (c *Client) monit(ctx context.Context, pubKey solana.PublicKey, commit rpc.CommitmentType) {
-	sub, err := c.WsClient.LogsSubscribeMentions(pubKey, commit)
+//
+// 返回订阅成功的时间(订阅失败时为零值)以及导致退出的错误
+func (c *Client) monit(ctx context.Context, ep *endpoint, pubKey solana.PublicKey, commit rpc.CommitmentType) (time.Time, error) {
+	sub, err := c.getWsClient(ep).LogsSubscribeMentions(pubKey, commit)
 	if err != nil {
-		log.Println("订阅失败,3秒后重试 | ", err)
-		time.Sleep(3 * time.Second)
-		return
+		return time.Time{}, fmt.Errorf("订阅失败: %w", err)
 	}
 	defer sub.Unsubscribe()
+	connectedAt := time.Now()
+	c.setState(ep, ConnStateConnected, 0, 0, nil)
+
+	// 订阅成功后再补齐, 补齐期间的实时日志会暂存在订阅中, 补齐完成后再按顺序处理
+	if c.Backfill {
+		count, err := c.backfill(ctx, pubKey, commit)
+		if err != nil {
+			log.Println("补齐断线期间的交易失败 | ", err)
+		} else if count > 0 {
+			log.Printf("补齐断线期间的交易 %d 笔", count)
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return connectedAt, ctx.Err()
 		default:
 			msg, err := sub.Recv(ctx)
 			if err != nil {
-				log.Println("WebSocket链接异常,重连中 | ", err)
-				return
+				return connectedAt, err
 			}
 
-			if msg.Value.Logs == nil || msg.Value.Err != nil {
-				continue // Skip this message.
+			if _, err := c.enqueue(ctx, msg, ep); err != nil {
+				return connectedAt, err
 			}
-			c.LogQuque <- msg
 		}
 	}
 }
 
-// 将管道中的日志进行分发
-func (c *Client) logProcess(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case log := <-c.LogQuque:
-			for _, apusic := range c.LogApusic {
-				go apusic(log) // 异步处理启动所有
+// enqueue 记录游标并去重后写入日志队列, 返回是否写入
+//
+// 失败的交易、重复的签名以及按 QueuePolicy 丢弃的日志不会写入; ep 为空表示来自补齐, 不计入端点统计
+func (c *Client) enqueue(ctx context.Context, msg *ws.LogResult, ep *endpoint) (bool, error) {
+	c.markSeen(msg)
+	if msg.Value.Logs == nil || msg.Value.Err != nil {
+		return false, nil // Skip this message.
+	}
+	if ep != nil {
+		ep.notifications.Add(1)
+	}
+	if c.Dedup != nil {
+		firstSeen, duplicate := c.Dedup.observe(msg.Value.Signature)
+		if duplicate {
+			if ep != nil {
+				ep.recordDelay(time.Since(firstSeen))
 			}
+			return false, nil
 		}
 	}
+	if ep != nil {
+		ep.wins.Add(1)
+		ep.recordDelay(0)
+	}
+
+	return c.push(ctx, msg)
+}
+
+// DedupStats 获取签名去重统计
+func (c *Client) DedupStats() DedupStats {
+	if c.Dedup == nil {
+		return DedupStats{}
+	}
+	return c.Dedup.Stats()
 }
@@ -0,0 +1,97 @@
+package bonk
+
+import (
+	"encoding/binary"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// launchpad程序使用的PDA种子, 与链上程序中的常量一致
+const (
+	AuthSeed           = "vault_auth_seed"   // AUTH_SEED
+	GlobalConfigSeed   = "global_config"     // GLOBAL_CONFIG_SEED
+	PoolSeed           = "pool"              // POOL_SEED
+	PoolVaultSeed      = "pool_vault"        // POOL_VAULT_SEED
+	PoolVestingSeed    = "pool_vesting"      // POOL_VESTING_SEED
+	PlatformConfigSeed = "platform_config"   // PLATFORM_CONFIG_SEED
+	EventAuthoritySeed = "__event_authority" // anchor emit_cpi! 使用的种子
+	MetadataSeed       = "metadata"          // Metaplex 元数据种子
+)
+
+// FindAuthorityPDA 派生池子金库的权限账户
+func FindAuthorityPDA() (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{
+		[]byte(AuthSeed),
+	}, raydium_launchpad.ProgramID)
+}
+
+// FindGlobalConfigPDA 派生全局配置账户
+//
+// quoteMint: quote代币, 例如 WSOL
+//
+// curveType: 曲线类型
+//
+// index: 同一曲线类型下可能存在多个配置, 通过index区分
+func FindGlobalConfigPDA(quoteMint solana.PublicKey, curveType CurveType, index uint16) (solana.PublicKey, uint8, error) {
+	indexBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(indexBytes, index)
+	return solana.FindProgramAddress([][]byte{
+		[]byte(GlobalConfigSeed),
+		quoteMint.Bytes(),
+		{uint8(curveType)},
+		indexBytes,
+	}, raydium_launchpad.ProgramID)
+}
+
+// FindPlatformConfigPDA 派生平台配置账户
+func FindPlatformConfigPDA(platformAdmin solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{
+		[]byte(PlatformConfigSeed),
+		platformAdmin.Bytes(),
+	}, raydium_launchpad.ProgramID)
+}
+
+// FindPoolStatePDA 派生池子状态账户
+func FindPoolStatePDA(baseMint, quoteMint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{
+		[]byte(PoolSeed),
+		baseMint.Bytes(),
+		quoteMint.Bytes(),
+	}, raydium_launchpad.ProgramID)
+}
+
+// FindPoolVaultPDA 派生池子的代币金库, mint 为 base 或 quote 代币
+func FindPoolVaultPDA(poolState, mint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{
+		[]byte(PoolVaultSeed),
+		poolState.Bytes(),
+		mint.Bytes(),
+	}, raydium_launchpad.ProgramID)
+}
+
+// FindVestingRecordPDA 派生受益人的锁仓记录账户
+func FindVestingRecordPDA(poolState, beneficiary solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{
+		[]byte(PoolVestingSeed),
+		poolState.Bytes(),
+		beneficiary.Bytes(),
+	}, raydium_launchpad.ProgramID)
+}
+
+// FindEventAuthorityPDA 派生 emit_cpi! 使用的 event authority 账户
+func FindEventAuthorityPDA() (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{
+		[]byte(EventAuthoritySeed),
+	}, raydium_launchpad.ProgramID)
+}
+
+// FindMetadataPDA 派生代币的 Metaplex 元数据账户
+func FindMetadataPDA(mint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{
+		[]byte(MetadataSeed),
+		solana.TokenMetadataProgramID.Bytes(),
+		mint.Bytes(),
+	}, solana.TokenMetadataProgramID)
+}
@@ -0,0 +1,94 @@
+package bonk
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	usdcMint = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+
+	raydiumAdmin = solana.MustPublicKeyFromBase58("GThUX1Atko4tqhN2NaiTazWSeFWMuiUvfFnyJyUghFMJ") // 程序管理员, 也是 Raydium 平台的管理员
+	uselessMint  = solana.MustPublicKeyFromBase58("Dz9mQ9NzkBcCsuGPFJ3r1bS4wgqKMHBPiVuniW8Mbonk") // 在 LaunchLab 发行的代币
+)
+
+func TestFindPDA(t *testing.T) {
+	tests := []struct {
+		name string
+		find func() (solana.PublicKey, uint8, error)
+		want string
+	}{
+		{"authority", FindAuthorityPDA, "WLHv2UAZm6z4KyaaELi5pjdbJh6RESMva1Rnn8pJVVh"},
+		{"event_authority", FindEventAuthorityPDA, "2DPAtwB8L12vrMRExbLuyGnC7n2J5LNoZQSejeQGpwkr"},
+		{"global_config", func() (solana.PublicKey, uint8, error) {
+			return FindGlobalConfigPDA(solana.SolMint, CurveType_Constant, 0)
+		}, "6s1xP3hpbAfFoNtUNF8mfHsjr2Bd97JxFJRWLbL6aHuX"},
+		{"metadata_usdc", func() (solana.PublicKey, uint8, error) {
+			return FindMetadataPDA(usdcMint)
+		}, "5x38Kp4hvdomTCnCrAny4UtMUt5rQBdB6px2K1Ui45Wq"},
+		{"metadata_wsol", func() (solana.PublicKey, uint8, error) {
+			return FindMetadataPDA(solana.SolMint)
+		}, "6dM4TqWyWJsbx7obrdLcviBkTafD5E8av61zfU6jq57X"},
+		// Raydium 平台的 PlatformConfig
+		{"platform_config", func() (solana.PublicKey, uint8, error) {
+			return FindPlatformConfigPDA(raydiumAdmin)
+		}, "4Bu96XjU84XjPDSpveTVf6LYGCkfW5FK7SNkREWcEfV4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := tt.find()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// uselessMint 的池子、金库和锁仓记录在主网上都存在, 应该与链上的账户对照.
+// TODO: 以下地址是本地派生的结果, 尚未与主网账户核对, 核对后用 getAccountInfo 确认的地址替换;
+// 在此之前只能防止种子或顺序被改动, 不能发现种子本身的错误
+func TestFindPoolPDA(t *testing.T) {
+	pool := solana.MustPublicKeyFromBase58("GWqWrb44KJ8rmKvytQVUDh9X2pAVUkT8zE5RqTnJ4Dw4")
+	tests := []struct {
+		name string
+		find func() (solana.PublicKey, uint8, error)
+		want string
+	}{
+		{"pool_state", func() (solana.PublicKey, uint8, error) {
+			return FindPoolStatePDA(uselessMint, solana.SolMint)
+		}, pool.String()},
+		{"base_vault", func() (solana.PublicKey, uint8, error) {
+			return FindPoolVaultPDA(pool, uselessMint)
+		}, "5dEFc3gHvWosaAgnMyMUv4vNGHJYgLGeeBUTas9X2to5"},
+		{"quote_vault", func() (solana.PublicKey, uint8, error) {
+			return FindPoolVaultPDA(pool, solana.SolMint)
+		}, "HLs4v849NpoVvFoekyvNakcSsra5hp8X3cvvcDoDSuia"},
+		{"vesting_record", func() (solana.PublicKey, uint8, error) {
+			return FindVestingRecordPDA(pool, raydiumAdmin)
+		}, "GJJAJfTcpCzWHV64sphfuhmSxnnGes1c6WAg8ntJdsBd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := tt.find()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// base 与 quote 的顺序不同, 派生出的池子也不同
+	reversed, _, err := FindPoolStatePDA(solana.SolMint, uselessMint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Equals(reversed) {
+		t.Fatal("base/quote 顺序不应得到相同的池子")
+	}
+}
@@ -0,0 +1,488 @@
+package bonk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/gosolana"
+	"github.com/go-enols/gosolana/ws"
+)
+
+const subscribeConcurrency = 16 // 同时发出的订阅请求数量
+
+// PoolUpdate PoolState 账户的一次变化
+type PoolUpdate struct {
+	PoolId      solana.PublicKey             `json:"pool_id"`
+	Slot        uint64                       `json:"slot"`
+	PoolState   *raydium_launchpad.PoolState `json:"pool_state"`
+	CurveType   CurveType                    `json:"curve_type"`
+	RealBase    uint64                       `json:"real_base"`
+	RealQuote   uint64                       `json:"real_quote"`
+	PoolMetrics                              // 募集进度和价格, 无法获取曲线类型时为零值
+}
+
+// PoolWatcher 通过 accountSubscribe 实时监听 PoolState 账户
+//
+// 使用 Client 的端点和重连策略, 每次订阅成功后会通过 getMultipleAccounts 补齐断线期间的变化;
+// 多个端点收到的同一个变化只输出一次
+type PoolWatcher struct {
+	Client     *Client            // 连接管理, 可以设置 ReconnectPolicy、OnStateChange
+	Commitment rpc.CommitmentType // 默认 confirmed
+	QuoteMint  solana.PublicKey   // WatchBaseMint 使用的报价代币, 默认 WSOL
+	Accounts   AccountLoader      // 读取 GlobalConfig, 设置为共享的 *RPC 时与其他组件的读取合并, 为 nil 时直接请求
+
+	// 监听的池子超过该数量时改为一个 programSubscribe 并在本地过滤, 0 表示始终使用 accountSubscribe;
+	// 每个订阅都会预分配较大的缓冲区, 同时订阅几百个账户会占用大量内存. 运行中 WatchPool 超过该数量时也会切换.
+	// 默认32; 切换后每个端点会收到程序下全部池子的每一次变化(每笔买卖一条), 不监听的池子在本地丢弃,
+	// 带宽和 JSON 解析的开销与监听的数量无关. 只监听几十个池子且内存充足时可以调大, 使用公共节点时注意其订阅数量限制
+	AccountSubscribeLimit int
+
+	Updates chan *PoolUpdate // Run 返回后关闭
+
+	lock       sync.Mutex
+	pools      map[solana.PublicKey]*watchedPool
+	sessions   map[*watchSession]struct{}
+	curveTypes map[solana.PublicKey]CurveType // GlobalConfig 对应的曲线类型
+}
+
+// watchedPool 监听中的池子最后输出的数据
+type watchedPool struct {
+	slot uint64
+	data []byte
+}
+
+// watchSession 一个端点上的一次订阅
+//
+// 写入 Updates 的协程都由 wg 跟踪, subscribe 返回前等待它们结束, 因此 Run 关闭 Updates 后不会再有写入
+type watchSession struct {
+	ctx    context.Context
+	client *ws.Client
+
+	lock    sync.Mutex
+	closed  bool
+	program bool // 使用 programSubscribe
+	subs    map[solana.PublicKey]*ws.AccountSubscription
+	wg      sync.WaitGroup
+	errs    chan error
+}
+
+// start 在会话中启动协程, 会话已经结束时返回 false; 调用时需要持有 lock
+func (s *watchSession) start(fn func()) bool {
+	if s.closed {
+		return false
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+// fail 结束订阅并触发重连
+func (s *watchSession) fail(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+// NewPoolWatcher 创建池子监听器, option 与 NewClient 相同
+func NewPoolWatcher(ctx context.Context, option ...gosolana.Option) *PoolWatcher {
+	return &PoolWatcher{
+		Client:                NewClient(ctx, option...),
+		Commitment:            rpc.CommitmentConfirmed,
+		QuoteMint:             solana.SolMint,
+		AccountSubscribeLimit: 32,
+		Updates:               make(chan *PoolUpdate, 1000),
+		pools:                 make(map[solana.PublicKey]*watchedPool),
+		sessions:              make(map[*watchSession]struct{}),
+		curveTypes:            make(map[solana.PublicKey]CurveType),
+	}
+}
+
+// WatchPool 开始监听池子, 可以在 Run 之前或运行中调用; 运行中添加的池子会在后台获取一次当前状态
+func (w *PoolWatcher) WatchPool(poolIds ...solana.PublicKey) {
+	var added []solana.PublicKey
+	w.lock.Lock()
+	for _, poolId := range poolIds {
+		if _, ok := w.pools[poolId]; !ok {
+			w.pools[poolId] = &watchedPool{}
+			added = append(added, poolId)
+		}
+	}
+	overLimit := w.AccountSubscribeLimit > 0 && len(w.pools) > w.AccountSubscribeLimit
+	sessions := w.activeSessions()
+	w.lock.Unlock()
+	if len(added) == 0 {
+		return
+	}
+
+	for _, session := range sessions {
+		session.lock.Lock()
+		program := session.program
+		session.program = program || overLimit
+		session.lock.Unlock()
+
+		var err error
+		switch {
+		case program:
+			continue
+		case overLimit:
+			err = w.switchToProgram(session)
+		default:
+			err = w.subscribePools(session, added)
+		}
+		if err != nil {
+			session.fail(err)
+		}
+	}
+
+	// 在会话的协程中获取当前状态, Run 返回前会等待它结束
+	for _, session := range sessions {
+		session.lock.Lock()
+		started := session.start(func() {
+			if err := w.refresh(session.ctx, added); err != nil {
+				log.Println("获取池子状态失败 | ", err)
+			}
+		})
+		session.lock.Unlock()
+		if started {
+			break
+		}
+	}
+}
+
+// WatchBaseMint 监听 base mint 与 QuoteMint 的池子, 返回池子地址
+func (w *PoolWatcher) WatchBaseMint(baseMint solana.PublicKey) (solana.PublicKey, error) {
+	poolId, _, err := FindPoolStatePDA(baseMint, w.QuoteMint)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	w.WatchPool(poolId)
+	return poolId, nil
+}
+
+// Unwatch 停止监听池子
+func (w *PoolWatcher) Unwatch(poolIds ...solana.PublicKey) {
+	w.lock.Lock()
+	for _, poolId := range poolIds {
+		delete(w.pools, poolId)
+	}
+	sessions := w.activeSessions()
+	w.lock.Unlock()
+
+	for _, session := range sessions {
+		session.lock.Lock()
+		for _, poolId := range poolIds {
+			if sub, ok := session.subs[poolId]; ok {
+				delete(session.subs, poolId)
+				sub.Unsubscribe()
+			}
+		}
+		session.lock.Unlock()
+	}
+}
+
+// Pools 监听中的池子
+func (w *PoolWatcher) Pools() []solana.PublicKey {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	result := make([]solana.PublicKey, 0, len(w.pools))
+	for poolId := range w.pools {
+		result = append(result, poolId)
+	}
+	return result
+}
+
+// Run 开始监听, 直到 ctx 结束、Client 关闭或全部端点重连失败, 返回后关闭 Updates
+func (w *PoolWatcher) Run(ctx context.Context) error {
+	defer close(w.Updates)
+	return w.Client.run(ctx, w.subscribe)
+}
+
+func (w *PoolWatcher) activeSessions() []*watchSession {
+	sessions := make([]*watchSession, 0, len(w.sessions))
+	for session := range w.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// subscribe 在端点上订阅全部池子, 订阅断开时返回
+func (w *PoolWatcher) subscribe(ctx context.Context, ep *endpoint) (time.Time, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	session := &watchSession{
+		ctx:    ctx,
+		client: w.Client.getWsClient(ep),
+		subs:   make(map[solana.PublicKey]*ws.AccountSubscription),
+		errs:   make(chan error, 1),
+	}
+
+	w.lock.Lock()
+	pools := make([]solana.PublicKey, 0, len(w.pools))
+	for poolId := range w.pools {
+		pools = append(pools, poolId)
+	}
+	program := w.AccountSubscribeLimit > 0 && len(pools) > w.AccountSubscribeLimit
+	session.program = program
+	w.sessions[session] = struct{}{}
+	w.lock.Unlock()
+
+	defer func() {
+		cancel()
+		w.lock.Lock()
+		delete(w.sessions, session)
+		w.lock.Unlock()
+
+		session.lock.Lock()
+		session.closed = true
+		for poolId, sub := range session.subs {
+			delete(session.subs, poolId)
+			sub.Unsubscribe()
+		}
+		session.lock.Unlock()
+		session.wg.Wait()
+	}()
+
+	var err error
+	if program {
+		err = w.subscribeProgram(session)
+	} else {
+		err = w.subscribePools(session, pools)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	connectedAt := time.Now()
+	w.Client.setState(ep, ConnStateConnected, 0, 0, nil)
+
+	// 订阅成功后再获取一次当前状态, 补齐订阅之前的变化
+	if err := w.refresh(ctx, pools); err != nil {
+		log.Println("获取池子状态失败 | ", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return connectedAt, ctx.Err()
+	case err := <-session.errs:
+		return connectedAt, err
+	}
+}
+
+// subscribePools 为每个池子建立 accountSubscribe
+func (w *PoolWatcher) subscribePools(session *watchSession, pools []solana.PublicKey) error {
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, subscribeConcurrency)
+		errs = make([]error, len(pools))
+	)
+	for i, poolId := range pools {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, poolId solana.PublicKey) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = w.subscribePool(session, poolId)
+		}(i, poolId)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (w *PoolWatcher) subscribePool(session *watchSession, poolId solana.PublicKey) error {
+	session.lock.Lock()
+	_, ok := session.subs[poolId]
+	session.lock.Unlock()
+	if ok {
+		return nil
+	}
+
+	sub, err := session.client.AccountSubscribe(poolId, w.Commitment)
+	if err != nil {
+		return fmt.Errorf("订阅池子 %s 失败: %w", poolId, err)
+	}
+	session.lock.Lock()
+	defer session.lock.Unlock()
+	if _, ok := session.subs[poolId]; ok || session.closed || session.program {
+		sub.Unsubscribe()
+		return nil
+	}
+	session.subs[poolId] = sub
+
+	session.start(func() {
+		for {
+			msg, err := sub.Recv(session.ctx)
+			if err == nil && msg == nil {
+				err = ws.ErrSubscriptionClosed
+			}
+			if err != nil {
+				session.lock.Lock()
+				current := session.subs[poolId] == sub
+				session.lock.Unlock()
+				if current && session.ctx.Err() == nil {
+					// 不是主动取消订阅, 连接已经断开
+					session.fail(fmt.Errorf("池子 %s 的订阅中断: %w", poolId, err))
+				}
+				return
+			}
+			w.handle(session.ctx, poolId, msg.Context.Slot, accountData(&msg.Value.Account))
+		}
+	})
+	return nil
+}
+
+// subscribeProgram 使用一个 programSubscribe 接收全部 PoolState 的变化, 只处理监听中的池子
+func (w *PoolWatcher) subscribeProgram(session *watchSession) error {
+	sub, err := session.client.ProgramSubscribeWithOpts(raydium_launchpad.ProgramID, w.Commitment, solana.EncodingBase64, PoolStateFilter())
+	if err != nil {
+		return fmt.Errorf("订阅程序账户失败: %w", err)
+	}
+	session.lock.Lock()
+	defer session.lock.Unlock()
+	started := session.start(func() {
+		defer sub.Unsubscribe()
+		for {
+			msg, err := sub.Recv(session.ctx)
+			if err == nil && msg == nil {
+				err = ws.ErrSubscriptionClosed
+			}
+			if err != nil {
+				if session.ctx.Err() == nil {
+					session.fail(fmt.Errorf("程序账户的订阅中断: %w", err))
+				}
+				return
+			}
+			w.handle(session.ctx, msg.Value.Pubkey, msg.Context.Slot, accountData(msg.Value.Account))
+		}
+	})
+	if !started {
+		sub.Unsubscribe()
+	}
+	return nil
+}
+
+// switchToProgram 监听的池子超过 AccountSubscribeLimit 后改为 programSubscribe, 再取消每个池子的订阅
+func (w *PoolWatcher) switchToProgram(session *watchSession) error {
+	if err := w.subscribeProgram(session); err != nil {
+		return err
+	}
+	session.lock.Lock()
+	defer session.lock.Unlock()
+	for poolId, sub := range session.subs {
+		delete(session.subs, poolId)
+		sub.Unsubscribe()
+	}
+	return nil
+}
+
+// refresh 通过 getMultipleAccounts 获取池子当前的状态
+//
+// 需要响应中的 slot 与订阅的通知比较先后, 因此不使用 Accounts.LoadAccounts, 而是直接使用共享 RPC 的客户端
+func (w *PoolWatcher) refresh(ctx context.Context, pools []solana.PublicKey) error {
+	client := rpcClient(w.Accounts, w.Client.rpcClient())
+	var errs []error
+	for start := 0; start < len(pools); start += maxMultipleAccounts {
+		end := min(start+maxMultipleAccounts, len(pools))
+		accounts, err := client.GetMultipleAccountsWithOpts(ctx, pools[start:end], &rpc.GetMultipleAccountsOpts{
+			Commitment: w.Commitment,
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i, account := range accounts.Value {
+			if account != nil {
+				w.handle(ctx, pools[start+i], accounts.Context.Slot, accountData(account))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handle 解码并输出池子的变化, 旧的 slot 和重复的数据会被忽略
+func (w *PoolWatcher) handle(ctx context.Context, poolId solana.PublicKey, slot uint64, data []byte) {
+	w.lock.Lock()
+	pool, ok := w.pools[poolId]
+	if !ok || slot < pool.slot || (slot == pool.slot && bytes.Equal(data, pool.data)) {
+		w.lock.Unlock()
+		return
+	}
+	pool.slot = slot
+	pool.data = data
+	w.lock.Unlock()
+
+	poolState, err := raydium_launchpad.ParseAccount_PoolState(data)
+	if err != nil {
+		log.Printf("解析池子 %s 失败 | %v", poolId, err)
+		return
+	}
+	update := &PoolUpdate{
+		PoolId:    poolId,
+		Slot:      slot,
+		PoolState: poolState,
+		RealBase:  poolState.RealBase,
+		RealQuote: poolState.RealQuote,
+	}
+	if curveType, err := w.curveType(ctx, poolState.GlobalConfig); err != nil {
+		log.Printf("获取池子 %s 的曲线类型失败 | %v", poolId, err)
+	} else {
+		update.CurveType = curveType
+		if metrics, err := CalculatePoolMetrics(poolState, curveType); err == nil {
+			update.PoolMetrics = *metrics
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+	case w.Updates <- update:
+	}
+}
+
+// curveType 获取 GlobalConfig 的曲线类型, 结果会被缓存
+func (w *PoolWatcher) curveType(ctx context.Context, globalConfig solana.PublicKey) (CurveType, error) {
+	w.lock.Lock()
+	curveType, ok := w.curveTypes[globalConfig]
+	w.lock.Unlock()
+	if ok {
+		return curveType, nil
+	}
+
+	accounts, err := loadAccounts(ctx, w.Accounts, w.Client.rpcClient(), globalConfig)
+	if err != nil {
+		return 0, err
+	}
+	if len(accounts) != 1 || accounts[0] == nil {
+		return 0, fmt.Errorf("全局配置 %s 不存在", globalConfig)
+	}
+	global, err := raydium_launchpad.ParseAccount_GlobalConfig(accounts[0].Data.GetBinary())
+	if err != nil {
+		return 0, fmt.Errorf("解析GlobalConfig失败: %w", err)
+	}
+	if global.CurveType > uint8(CurveType_Linear) {
+		return 0, fmt.Errorf("未知的曲线类型: %d", global.CurveType)
+	}
+	curveType = CurveType(global.CurveType)
+
+	w.lock.Lock()
+	w.curveTypes[globalConfig] = curveType
+	w.lock.Unlock()
+	return curveType, nil
+}
+
+// accountData 账户数据, 账户不存在时为空
+func accountData(account *rpc.Account) []byte {
+	if account == nil || account.Data == nil {
+		return nil
+	}
+	return account.Data.GetBinary()
+}
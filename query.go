@@ -0,0 +1,144 @@
+package bonk
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// PoolState 账户的 Borsh 布局, 偏移量包含 8 字节的 discriminator
+const (
+	PoolStateEpochOffset                 = 8
+	PoolStateAuthBumpOffset              = PoolStateEpochOffset + 8
+	PoolStateStatusOffset                = PoolStateAuthBumpOffset + 1
+	PoolStateBaseDecimalsOffset          = PoolStateStatusOffset + 1
+	PoolStateQuoteDecimalsOffset         = PoolStateBaseDecimalsOffset + 1
+	PoolStateMigrateTypeOffset           = PoolStateQuoteDecimalsOffset + 1
+	PoolStateSupplyOffset                = PoolStateMigrateTypeOffset + 1
+	PoolStateTotalBaseSellOffset         = PoolStateSupplyOffset + 8
+	PoolStateVirtualBaseOffset           = PoolStateTotalBaseSellOffset + 8
+	PoolStateVirtualQuoteOffset          = PoolStateVirtualBaseOffset + 8
+	PoolStateRealBaseOffset              = PoolStateVirtualQuoteOffset + 8
+	PoolStateRealQuoteOffset             = PoolStateRealBaseOffset + 8
+	PoolStateTotalQuoteFundRaisingOffset = PoolStateRealQuoteOffset + 8
+	PoolStateQuoteProtocolFeeOffset      = PoolStateTotalQuoteFundRaisingOffset + 8
+	PoolStatePlatformFeeOffset           = PoolStateQuoteProtocolFeeOffset + 8
+	PoolStateMigrateFeeOffset            = PoolStatePlatformFeeOffset + 8
+	PoolStateVestingScheduleOffset       = PoolStateMigrateFeeOffset + 8
+	PoolStateGlobalConfigOffset          = PoolStateVestingScheduleOffset + 5*8
+	PoolStatePlatformConfigOffset        = PoolStateGlobalConfigOffset + solana.PublicKeyLength
+	PoolStateBaseMintOffset              = PoolStatePlatformConfigOffset + solana.PublicKeyLength
+	PoolStateQuoteMintOffset             = PoolStateBaseMintOffset + solana.PublicKeyLength
+	PoolStateBaseVaultOffset             = PoolStateQuoteMintOffset + solana.PublicKeyLength
+	PoolStateQuoteVaultOffset            = PoolStateBaseVaultOffset + solana.PublicKeyLength
+	PoolStateCreatorOffset               = PoolStateQuoteVaultOffset + solana.PublicKeyLength
+	PoolStatePaddingOffset               = PoolStateCreatorOffset + solana.PublicKeyLength
+	PoolStateSize                        = PoolStatePaddingOffset + 8*8
+)
+
+// PoolStateFilter 只匹配 PoolState 账户
+func PoolStateFilter() []rpc.RPCFilter {
+	return []rpc.RPCFilter{
+		{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: raydium_launchpad.Account_PoolState[:]}},
+		{DataSize: PoolStateSize},
+	}
+}
+
+func memcmpFilter(offset uint64, data []byte) rpc.RPCFilter {
+	return rpc.RPCFilter{Memcmp: &rpc.RPCFilterMemcmp{Offset: offset, Bytes: data}}
+}
+
+// FilterStatus 按池子状态过滤
+func FilterStatus(status raydium_launchpad.PoolStatus) rpc.RPCFilter {
+	return memcmpFilter(PoolStateStatusOffset, []byte{uint8(status)})
+}
+
+// FilterMigrateType 按迁移类型过滤, 0 为 AMM, 1 为 CPSWAP
+func FilterMigrateType(migrateType uint8) rpc.RPCFilter {
+	return memcmpFilter(PoolStateMigrateTypeOffset, []byte{migrateType})
+}
+
+// FilterTotalQuoteFundRaising 按募集目标过滤
+func FilterTotalQuoteFundRaising(amount uint64) rpc.RPCFilter {
+	return memcmpFilter(PoolStateTotalQuoteFundRaisingOffset, binary.LittleEndian.AppendUint64(nil, amount))
+}
+
+// FilterGlobalConfig 按全局配置过滤
+func FilterGlobalConfig(globalConfig solana.PublicKey) rpc.RPCFilter {
+	return memcmpFilter(PoolStateGlobalConfigOffset, globalConfig.Bytes())
+}
+
+// FilterPlatformConfig 按平台配置过滤
+func FilterPlatformConfig(platformConfig solana.PublicKey) rpc.RPCFilter {
+	return memcmpFilter(PoolStatePlatformConfigOffset, platformConfig.Bytes())
+}
+
+// FilterBaseMint 按 base mint 过滤
+func FilterBaseMint(baseMint solana.PublicKey) rpc.RPCFilter {
+	return memcmpFilter(PoolStateBaseMintOffset, baseMint.Bytes())
+}
+
+// FilterQuoteMint 按报价代币过滤
+func FilterQuoteMint(quoteMint solana.PublicKey) rpc.RPCFilter {
+	return memcmpFilter(PoolStateQuoteMintOffset, quoteMint.Bytes())
+}
+
+// FilterCreator 按创建者过滤
+func FilterCreator(creator solana.PublicKey) rpc.RPCFilter {
+	return memcmpFilter(PoolStateCreatorOffset, creator.Bytes())
+}
+
+// QueryPools 通过 getProgramAccounts 查询满足全部过滤器的池子, 只下载匹配的账户
+//
+// 返回的 Slot 为查询前获取的 slot, 账户数据至少和该 slot 一样新; 无法解析的账户会被跳过并记录日志
+func QueryPools(ctx context.Context, client *rpc.Client, commitment rpc.CommitmentType, filters ...rpc.RPCFilter) ([]*RegisteredPool, error) {
+	_, pools, err := queryPools(ctx, client, commitment, filters...)
+	return pools, err
+}
+
+// queryPools 同 QueryPools, 另外返回查询前获取的 slot
+func queryPools(ctx context.Context, client *rpc.Client, commitment rpc.CommitmentType, filters ...rpc.RPCFilter) (uint64, []*RegisteredPool, error) {
+	slot, err := client.GetSlot(ctx, commitment)
+	if err != nil {
+		return 0, nil, fmt.Errorf("获取slot失败: %w", err)
+	}
+	accounts, err := client.GetProgramAccountsWithOpts(ctx, raydium_launchpad.ProgramID, &rpc.GetProgramAccountsOpts{
+		Commitment: commitment,
+		Filters:    append(PoolStateFilter(), filters...),
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("查询池子失败: %w", err)
+	}
+
+	pools := make([]*RegisteredPool, 0, len(accounts))
+	for _, account := range accounts {
+		poolState, err := raydium_launchpad.ParseAccount_PoolState(accountData(account.Account))
+		if err != nil {
+			log.Printf("解析池子 %s 失败, 跳过 | %v", account.Pubkey, err)
+			continue
+		}
+		pools = append(pools, &RegisteredPool{PoolId: account.Pubkey, Slot: slot, PoolState: poolState})
+	}
+	return slot, pools, nil
+}
+
+// QueryPoolsByPlatformConfig 查询平台的全部池子
+func QueryPoolsByPlatformConfig(ctx context.Context, client *rpc.Client, platformConfig solana.PublicKey) ([]*RegisteredPool, error) {
+	return QueryPools(ctx, client, rpc.CommitmentConfirmed, FilterPlatformConfig(platformConfig))
+}
+
+// QueryPoolsByStatus 查询指定状态的全部池子, 例如仍在募集中的 PoolStatus_Fund
+func QueryPoolsByStatus(ctx context.Context, client *rpc.Client, status raydium_launchpad.PoolStatus) ([]*RegisteredPool, error) {
+	return QueryPools(ctx, client, rpc.CommitmentConfirmed, FilterStatus(status))
+}
+
+// QueryPoolsByCreator 查询创建者的全部池子
+func QueryPoolsByCreator(ctx context.Context, client *rpc.Client, creator solana.PublicKey) ([]*RegisteredPool, error) {
+	return QueryPools(ctx, client, rpc.CommitmentConfirmed, FilterCreator(creator))
+}
@@ -0,0 +1,126 @@
+package bonk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestPoolStateOffsets(t *testing.T) {
+	poolState := raydium_launchpad.PoolState{
+		Epoch:                 0x0101010101010101,
+		AuthBump:              0x02,
+		Status:                0x03,
+		BaseDecimals:          0x04,
+		QuoteDecimals:         0x05,
+		MigrateType:           0x06,
+		Supply:                0x0707070707070707,
+		TotalBaseSell:         0x0808080808080808,
+		VirtualBase:           0x0909090909090909,
+		VirtualQuote:          0x0a0a0a0a0a0a0a0a,
+		RealBase:              0x0b0b0b0b0b0b0b0b,
+		RealQuote:             0x0c0c0c0c0c0c0c0c,
+		TotalQuoteFundRaising: 0x0d0d0d0d0d0d0d0d,
+		QuoteProtocolFee:      0x0e0e0e0e0e0e0e0e,
+		PlatformFee:           0x0f0f0f0f0f0f0f0f,
+		MigrateFee:            0x1010101010101010,
+		GlobalConfig:          solana.NewWallet().PublicKey(),
+		PlatformConfig:        solana.NewWallet().PublicKey(),
+		BaseMint:              solana.NewWallet().PublicKey(),
+		QuoteMint:             solana.NewWallet().PublicKey(),
+		BaseVault:             solana.NewWallet().PublicKey(),
+		QuoteVault:            solana.NewWallet().PublicKey(),
+		Creator:               solana.NewWallet().PublicKey(),
+	}
+	body, err := poolState.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(raydium_launchpad.Account_PoolState[:], body...)
+	if len(data) != PoolStateSize {
+		t.Fatalf("PoolStateSize = %d, 实际 %d", PoolStateSize, len(data))
+	}
+
+	u64 := func(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+	tests := []struct {
+		name   string
+		offset int
+		want   []byte
+	}{
+		{"epoch", PoolStateEpochOffset, u64(poolState.Epoch)},
+		{"auth_bump", PoolStateAuthBumpOffset, []byte{poolState.AuthBump}},
+		{"status", PoolStateStatusOffset, []byte{poolState.Status}},
+		{"base_decimals", PoolStateBaseDecimalsOffset, []byte{poolState.BaseDecimals}},
+		{"quote_decimals", PoolStateQuoteDecimalsOffset, []byte{poolState.QuoteDecimals}},
+		{"migrate_type", PoolStateMigrateTypeOffset, []byte{poolState.MigrateType}},
+		{"supply", PoolStateSupplyOffset, u64(poolState.Supply)},
+		{"total_base_sell", PoolStateTotalBaseSellOffset, u64(poolState.TotalBaseSell)},
+		{"virtual_base", PoolStateVirtualBaseOffset, u64(poolState.VirtualBase)},
+		{"virtual_quote", PoolStateVirtualQuoteOffset, u64(poolState.VirtualQuote)},
+		{"real_base", PoolStateRealBaseOffset, u64(poolState.RealBase)},
+		{"real_quote", PoolStateRealQuoteOffset, u64(poolState.RealQuote)},
+		{"total_quote_fund_raising", PoolStateTotalQuoteFundRaisingOffset, u64(poolState.TotalQuoteFundRaising)},
+		{"quote_protocol_fee", PoolStateQuoteProtocolFeeOffset, u64(poolState.QuoteProtocolFee)},
+		{"platform_fee", PoolStatePlatformFeeOffset, u64(poolState.PlatformFee)},
+		{"migrate_fee", PoolStateMigrateFeeOffset, u64(poolState.MigrateFee)},
+		{"global_config", PoolStateGlobalConfigOffset, poolState.GlobalConfig.Bytes()},
+		{"platform_config", PoolStatePlatformConfigOffset, poolState.PlatformConfig.Bytes()},
+		{"base_mint", PoolStateBaseMintOffset, poolState.BaseMint.Bytes()},
+		{"quote_mint", PoolStateQuoteMintOffset, poolState.QuoteMint.Bytes()},
+		{"base_vault", PoolStateBaseVaultOffset, poolState.BaseVault.Bytes()},
+		{"quote_vault", PoolStateQuoteVaultOffset, poolState.QuoteVault.Bytes()},
+		{"creator", PoolStateCreatorOffset, poolState.Creator.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := data[tt.offset : tt.offset+len(tt.want)]
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("偏移 %d 的数据为 %x, 应为 %x", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolFilters(t *testing.T) {
+	creator := solana.NewWallet().PublicKey()
+	poolState := raydium_launchpad.PoolState{
+		Status:         uint8(raydium_launchpad.PoolStatus_Migrate),
+		PlatformConfig: solana.NewWallet().PublicKey(),
+		Creator:        creator,
+	}
+	body, err := poolState.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(raydium_launchpad.Account_PoolState[:], body...)
+
+	tests := []struct {
+		name   string
+		filter rpc.RPCFilter
+		match  bool
+	}{
+		{"discriminator", PoolStateFilter()[0], true},
+		{"status", FilterStatus(raydium_launchpad.PoolStatus_Migrate), true},
+		{"status_fund", FilterStatus(raydium_launchpad.PoolStatus_Fund), false},
+		{"platform_config", FilterPlatformConfig(poolState.PlatformConfig), true},
+		{"creator", FilterCreator(creator), true},
+		{"other_creator", FilterCreator(solana.NewWallet().PublicKey()), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, want := tt.filter.Memcmp.Offset, tt.filter.Memcmp.Bytes
+			got := bytes.Equal(data[offset:offset+uint64(len(want))], want)
+			if got != tt.match {
+				t.Errorf("匹配结果为 %v, 应为 %v", got, tt.match)
+			}
+		})
+	}
+	if size := PoolStateFilter()[1].DataSize; size != uint64(len(data)) {
+		t.Errorf("DataSize = %d, 实际 %d", size, len(data))
+	}
+}
@@ -0,0 +1,273 @@
+package bonk
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+)
+
+// FeeRateDenominator 手续费率的分母, 费率 10000 表示 1%
+const FeeRateDenominator = 1_000_000
+
+// BpsDenominator 滑点的分母, 100 bps 表示 1%
+const BpsDenominator = 10_000
+
+// FeeBreakdown 一笔交易的手续费明细, 手续费全部以 quote 计价
+type FeeBreakdown struct {
+	ProtocolFee uint64 `json:"protocol_fee"` // GlobalConfig.TradeFeeRate
+	PlatformFee uint64 `json:"platform_fee"` // PlatformConfig.FeeRate
+	ShareFee    uint64 `json:"share_fee"`    // 分享者手续费
+	TotalFee    uint64 `json:"total_fee"`
+}
+
+// Quote 离线报价结果
+//
+// 买入时 AmountIn 为 quote, AmountOut 为 base; 卖出时相反
+// 买入的 AmountIn 和卖出的 AmountOut 已经包含手续费
+type Quote struct {
+	AmountIn  uint64       `json:"amount_in"`
+	AmountOut uint64       `json:"amount_out"`
+	Fee       FeeBreakdown `json:"fee"`
+}
+
+// MinimumAmountOut 根据滑点计算 minimum_amount_out 参数
+func (q *Quote) MinimumAmountOut(slippageBps uint64) uint64 {
+	return MinimumAmountOut(q.AmountOut, slippageBps)
+}
+
+// MaximumAmountIn 根据滑点计算 maximum_amount_in 参数
+func (q *Quote) MaximumAmountIn(slippageBps uint64) uint64 {
+	return MaximumAmountIn(q.AmountIn, slippageBps)
+}
+
+// MinimumAmountOut amount * (1 - slippage), 向下取整
+func MinimumAmountOut(amount, slippageBps uint64) uint64 {
+	if slippageBps >= BpsDenominator {
+		return 0
+	}
+	result := new(big.Int).Mul(u64(amount), u64(BpsDenominator-slippageBps))
+	return result.Quo(result, u64(BpsDenominator)).Uint64()
+}
+
+// MaximumAmountIn amount * (1 + slippage), 向上取整, 溢出时返回最大值
+func MaximumAmountIn(amount, slippageBps uint64) uint64 {
+	result := ceilDiv(new(big.Int).Mul(u64(amount), new(big.Int).Add(u64(BpsDenominator), u64(slippageBps))), u64(BpsDenominator))
+	if !result.IsUint64() {
+		return ^uint64(0)
+	}
+	return result.Uint64()
+}
+
+// Quoter 基于 PoolState 的离线报价器, 计算方式与链上程序一致
+type Quoter struct {
+	Pool            *raydium_launchpad.PoolState
+	CurveType       CurveType
+	TradeFeeRate    uint64 // GlobalConfig.TradeFeeRate
+	PlatformFeeRate uint64 // PlatformConfig.FeeRate
+	ShareFeeRate    uint64 // 交易指令中的 share_fee_rate
+}
+
+// NewQuoter 创建报价器, platform 为空时平台手续费为0
+func NewQuoter(pool *raydium_launchpad.PoolState, global *raydium_launchpad.GlobalConfig, platform *raydium_launchpad.PlatformConfig, shareFeeRate uint64) (*Quoter, error) {
+	if pool == nil || global == nil {
+		return nil, errors.New("池子状态或全局配置为空")
+	}
+	if global.CurveType > uint8(CurveType_Linear) {
+		return nil, fmt.Errorf("未知的曲线类型: %d", global.CurveType)
+	}
+	quoter := &Quoter{
+		Pool:         pool,
+		CurveType:    CurveType(global.CurveType),
+		TradeFeeRate: global.TradeFeeRate,
+		ShareFeeRate: shareFeeRate,
+	}
+	if platform != nil {
+		quoter.PlatformFeeRate = platform.FeeRate
+	}
+	return quoter, nil
+}
+
+// totalFeeRate 总手续费率
+func (q *Quoter) totalFeeRate() (uint64, error) {
+	total := q.TradeFeeRate + q.PlatformFeeRate + q.ShareFeeRate
+	if total >= FeeRateDenominator {
+		return 0, fmt.Errorf("手续费率之和过大: %d", total)
+	}
+	return total, nil
+}
+
+// calculateFee amount * rate / 1e6, 向上取整
+func calculateFee(amount *big.Int, rate uint64) *big.Int {
+	return ceilDiv(new(big.Int).Mul(amount, u64(rate)), u64(FeeRateDenominator))
+}
+
+// calculatePreFee 根据扣除手续费后的数量反推扣除前的数量, 向上取整
+func calculatePreFee(postFee *big.Int, rate uint64) *big.Int {
+	if rate == 0 {
+		return new(big.Int).Set(postFee)
+	}
+	return ceilDiv(new(big.Int).Mul(postFee, u64(FeeRateDenominator)), u64(FeeRateDenominator-rate))
+}
+
+// fees 与链上程序一样分别计算协议、平台和分享者手续费, 每项向上取整, 总手续费为三项之和
+func (q *Quoter) fees(amount *big.Int) (protocolFee, platformFee, shareFee, totalFee *big.Int) {
+	protocolFee = calculateFee(amount, q.TradeFeeRate)
+	platformFee = calculateFee(amount, q.PlatformFeeRate)
+	shareFee = calculateFee(amount, q.ShareFeeRate)
+	totalFee = new(big.Int).Add(protocolFee, platformFee)
+	totalFee.Add(totalFee, shareFee)
+	return protocolFee, platformFee, shareFee, totalFee
+}
+
+// preFee 反推扣除手续费前的数量, 保证扣除分别取整的手续费后不少于 postFee
+//
+// 分别取整的手续费之和可能比按总费率计算的多 1~2, 不足时补上差额
+func (q *Quoter) preFee(postFee *big.Int, rate uint64) *big.Int {
+	amount := calculatePreFee(postFee, rate)
+	for {
+		_, _, _, totalFee := q.fees(amount)
+		deficit := new(big.Int).Sub(postFee, new(big.Int).Sub(amount, totalFee))
+		if deficit.Sign() <= 0 {
+			return amount
+		}
+		amount.Add(amount, deficit)
+	}
+}
+
+// newQuote 对 feeBase(买入为花费的 quote, 卖出为扣除手续费前的 quote)计算手续费,
+// 检查结果是否溢出 u64 并生成报价
+func (q *Quoter) newQuote(amountIn, amountOut, feeBase *big.Int) (*Quote, error) {
+	protocolFee, platformFee, shareFee, totalFee := q.fees(feeBase)
+	for _, v := range []*big.Int{amountIn, amountOut, totalFee} {
+		if v.Sign() < 0 || !v.IsUint64() {
+			return nil, fmt.Errorf("报价结果超出范围: %s", v)
+		}
+	}
+	return &Quote{
+		AmountIn:  amountIn.Uint64(),
+		AmountOut: amountOut.Uint64(),
+		Fee: FeeBreakdown{
+			ProtocolFee: protocolFee.Uint64(),
+			PlatformFee: platformFee.Uint64(),
+			ShareFee:    shareFee.Uint64(),
+			TotalFee:    totalFee.Uint64(),
+		},
+	}, nil
+}
+
+func (q *Quoter) prepare() (curveCalculator, uint64, error) {
+	if q.Pool == nil {
+		return nil, 0, errors.New("池子状态为空")
+	}
+	curve, err := getCurveCalculator(q.CurveType)
+	if err != nil {
+		return nil, 0, err
+	}
+	rate, err := q.totalFeeRate()
+	if err != nil {
+		return nil, 0, err
+	}
+	return curve, rate, nil
+}
+
+// remainingBase 池子剩余可卖出的 base 数量
+func remainingBase(pool *raydium_launchpad.PoolState) *big.Int {
+	remaining := new(big.Int).Sub(u64(pool.TotalBaseSell), u64(pool.RealBase))
+	if remaining.Sign() < 0 {
+		return big.NewInt(0)
+	}
+	return remaining
+}
+
+// BuyExactIn 使用 amountIn 个 quote 买入, 返回可获得的 base
+//
+// 超出池子剩余可卖数量时, 只买入剩余部分并重新计算实际花费的 quote
+func (q *Quoter) BuyExactIn(amountIn uint64) (*Quote, error) {
+	curve, rate, err := q.prepare()
+	if err != nil {
+		return nil, err
+	}
+	quoteIn := u64(amountIn)
+	_, _, _, totalFee := q.fees(quoteIn)
+	lessFee := new(big.Int).Sub(quoteIn, totalFee)
+	if lessFee.Sign() < 0 {
+		return nil, fmt.Errorf("买入数量不足以支付手续费: %d", amountIn)
+	}
+	amountOut, err := curve.buyExactIn(q.Pool, lessFee)
+	if err != nil {
+		return nil, err
+	}
+
+	if remaining := remainingBase(q.Pool); amountOut.Cmp(remaining) > 0 {
+		amountOut = remaining
+		lessFee, err := curve.buyExactOut(q.Pool, amountOut)
+		if err != nil {
+			return nil, err
+		}
+		quoteIn = q.preFee(lessFee, rate)
+	}
+	return q.newQuote(quoteIn, amountOut, quoteIn)
+}
+
+// BuyExactOut 买入 amountOut 个 base, 返回需要花费的 quote
+//
+// 超出池子剩余可卖数量时, 只买入剩余部分
+func (q *Quoter) BuyExactOut(amountOut uint64) (*Quote, error) {
+	curve, rate, err := q.prepare()
+	if err != nil {
+		return nil, err
+	}
+	baseOut := u64(amountOut)
+	if remaining := remainingBase(q.Pool); baseOut.Cmp(remaining) > 0 {
+		baseOut = remaining
+	}
+	lessFee, err := curve.buyExactOut(q.Pool, baseOut)
+	if err != nil {
+		return nil, err
+	}
+	quoteIn := q.preFee(lessFee, rate)
+	return q.newQuote(quoteIn, baseOut, quoteIn)
+}
+
+// SellExactIn 卖出 amountIn 个 base, 返回扣除手续费后可获得的 quote
+func (q *Quoter) SellExactIn(amountIn uint64) (*Quote, error) {
+	curve, _, err := q.prepare()
+	if err != nil {
+		return nil, err
+	}
+	baseIn := u64(amountIn)
+	if baseIn.Cmp(u64(q.Pool.RealBase)) > 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	quoteOut, err := curve.sellExactIn(q.Pool, baseIn)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, totalFee := q.fees(quoteOut)
+	return q.newQuote(baseIn, new(big.Int).Sub(quoteOut, totalFee), quoteOut)
+}
+
+// SellExactOut 卖出获得 amountOut 个 quote(扣除手续费后), 返回需要卖出的 base
+//
+// 手续费分别取整, 实际获得的 quote 可能比 amountOut 多 1~2
+func (q *Quoter) SellExactOut(amountOut uint64) (*Quote, error) {
+	curve, rate, err := q.prepare()
+	if err != nil {
+		return nil, err
+	}
+	withFee := q.preFee(u64(amountOut), rate)
+	if withFee.Cmp(u64(q.Pool.RealQuote)) > 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	baseIn, err := curve.sellExactOut(q.Pool, withFee)
+	if err != nil {
+		return nil, err
+	}
+	if baseIn.Cmp(u64(q.Pool.RealBase)) > 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	_, _, _, totalFee := q.fees(withFee)
+	return q.newQuote(baseIn, new(big.Int).Sub(withFee, totalFee), withFee)
+}
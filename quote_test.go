@@ -0,0 +1,125 @@
+package bonk
+
+import (
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+)
+
+// bonkPool letsbonk.fun 默认参数的恒定乘积曲线池子, 募集 85 SOL
+func bonkPool(realBase, realQuote uint64) *raydium_launchpad.PoolState {
+	return &raydium_launchpad.PoolState{
+		Supply:                1_000_000_000_000_000,
+		TotalBaseSell:         793_100_000_000_000,
+		VirtualBase:           1_073_025_605_596_382,
+		VirtualQuote:          30_000_852_951,
+		RealBase:              realBase,
+		RealQuote:             realQuote,
+		TotalQuoteFundRaising: 85_000_000_000,
+	}
+}
+
+func TestQuoter(t *testing.T) {
+	var (
+		constant    = bonkPool(200_000_000_000_000, 6_872_846_056)
+		constantCap = bonkPool(793_099_000_000_000, 84_999_589_175) // 只剩 1000 个代币
+		fixed       = &raydium_launchpad.PoolState{
+			TotalBaseSell: 500_000_000_000_000,
+			VirtualBase:   1_000_000_000,
+			VirtualQuote:  37,
+			RealBase:      100_000_000_000_000,
+			RealQuote:     3_700_000,
+		}
+		linear = &raydium_launchpad.PoolState{
+			TotalBaseSell: 793_100_000_000_000,
+			VirtualBase:   4985, // 卖完 TotalBaseSell 约募集 85 SOL
+			RealBase:      300_000_000_000_000,
+			RealQuote:     12_160_682_617_141,
+		}
+	)
+
+	// 期望值按链上程序的公式独立计算, 协议、平台、分享者手续费分别向上取整
+	tests := []struct {
+		name      string
+		pool      *raydium_launchpad.PoolState
+		curveType CurveType
+		quote     func(*Quoter, uint64) (*Quote, error)
+		amount    uint64
+		want      Quote
+	}{
+		{"constant_buy_exact_in", constant, CurveType_Constant, (*Quoter).BuyExactIn, 1_000_000_000,
+			Quote{1_000_000_000, 22_747_893_104_354, FeeBreakdown{2_500_000, 10_000_000, 1_000_000, 13_500_000}}},
+		{"constant_buy_exact_out", constant, CurveType_Constant, (*Quoter).BuyExactOut, 1_000_000_000_000,
+			Quote{42_863_773, 1_000_000_000_000, FeeBreakdown{107_160, 428_638, 42_864, 578_662}}},
+		{"constant_sell_exact_in", constant, CurveType_Constant, (*Quoter).SellExactIn, 1_000_000_000_000,
+			Quote{1_000_000_000_000, 41_618_806, FeeBreakdown{105_471, 421_884, 42_189, 569_544}}},
+		{"constant_sell_exact_out", constant, CurveType_Constant, (*Quoter).SellExactOut, 100_000_000,
+			Quote{2_406_626_772_641, 100_000_000, FeeBreakdown{253_422, 1_013_685, 101_369, 1_368_476}}},
+		// 按总费率取整只有 14
+		{"constant_fee_rounding", constant, CurveType_Constant, (*Quoter).BuyExactIn, 1001,
+			Quote{1001, 23_320_963, FeeBreakdown{3, 11, 2, 16}}},
+		{"constant_buy_exact_in_capped", constantCap, CurveType_Constant, (*Quoter).BuyExactIn, 10_000_000_000,
+			Quote{416_450, 1_000_000_000, FeeBreakdown{1042, 4165, 417, 5624}}},
+		{"constant_buy_exact_out_capped", constantCap, CurveType_Constant, (*Quoter).BuyExactOut, 1_000_000_000_000,
+			Quote{416_450, 1_000_000_000, FeeBreakdown{1042, 4165, 417, 5624}}},
+
+		{"fixed_buy_exact_in", fixed, CurveType_Fixed, (*Quoter).BuyExactIn, 100_000,
+			Quote{100_000, 2_666_216_216_216, FeeBreakdown{250, 1000, 100, 1350}}},
+		{"fixed_buy_exact_in_capped", fixed, CurveType_Fixed, (*Quoter).BuyExactIn, 1_000_000_000,
+			Quote{15_002_536, 400_000_000_000_000, FeeBreakdown{37_507, 150_026, 15_003, 202_536}}},
+		{"fixed_buy_exact_out", fixed, CurveType_Fixed, (*Quoter).BuyExactOut, 1_000_000_000_000,
+			Quote{37_508, 1_000_000_000_000, FeeBreakdown{94, 376, 38, 508}}},
+		{"fixed_sell_exact_in", fixed, CurveType_Fixed, (*Quoter).SellExactIn, 1_000_000_000_000,
+			Quote{1_000_000_000_000, 36_500, FeeBreakdown{93, 370, 37, 500}}},
+		{"fixed_sell_exact_out", fixed, CurveType_Fixed, (*Quoter).SellExactOut, 10_000,
+			Quote{274_027_027_028, 10_000, FeeBreakdown{26, 102, 11, 139}}},
+
+		{"linear_buy_exact_in", linear, CurveType_Linear, (*Quoter).BuyExactIn, 1_000_000_000,
+			Quote{1_000_000_000, 12_168_066_862, FeeBreakdown{2_500_000, 10_000_000, 1_000_000, 13_500_000}}},
+		{"linear_buy_exact_out", linear, CurveType_Linear, (*Quoter).BuyExactOut, 1_000_000_000_000,
+			Quote{82_317_624_070, 1_000_000_000_000, FeeBreakdown{205_794_061, 823_176_241, 82_317_625, 1_111_287_927}}},
+		{"linear_sell_exact_in", linear, CurveType_Linear, (*Quoter).SellExactIn, 1_000_000_000_000,
+			Quote{1_000_000_000_000, 79_843_461_418, FeeBreakdown{202_340_247, 809_360_988, 80_936_099, 1_092_637_334}}},
+		{"linear_sell_exact_out", linear, CurveType_Linear, (*Quoter).SellExactOut, 100_000_000,
+			Quote{1_250_365_913, 100_000_000, FeeBreakdown{253_422, 1_013_685, 101_369, 1_368_476}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quoter := &Quoter{
+				Pool:            tt.pool,
+				CurveType:       tt.curveType,
+				TradeFeeRate:    2500,
+				PlatformFeeRate: 10000,
+				ShareFeeRate:    1000,
+			}
+			got, err := tt.quote(quoter, tt.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Fatalf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoterErrors(t *testing.T) {
+	quoter := &Quoter{
+		Pool:            bonkPool(200_000_000_000_000, 6_872_846_056),
+		CurveType:       CurveType_Constant,
+		TradeFeeRate:    2500,
+		PlatformFeeRate: 10000,
+		ShareFeeRate:    1000,
+	}
+	// 三项手续费各自向上取整为 1, 超过花费的 quote
+	if _, err := quoter.BuyExactIn(1); err == nil {
+		t.Error("BuyExactIn(1) error = nil")
+	}
+	if _, err := quoter.SellExactIn(300_000_000_000_000); err != ErrInsufficientLiquidity {
+		t.Errorf("SellExactIn() error = %v, want ErrInsufficientLiquidity", err)
+	}
+	quoter.ShareFeeRate = FeeRateDenominator
+	if _, err := quoter.BuyExactIn(1_000_000_000); err == nil {
+		t.Error("BuyExactIn() with fee rate >= 100% error = nil")
+	}
+}
@@ -0,0 +1,308 @@
+package bonk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/gosolana"
+	"github.com/go-enols/gosolana/ws"
+)
+
+// RegisteredPool 注册表中的一个池子, 不会被修改, 池子变化时替换为新的值
+type RegisteredPool struct {
+	PoolId    solana.PublicKey             `json:"pool_id"`
+	Slot      uint64                       `json:"slot"`
+	PoolState *raydium_launchpad.PoolState `json:"pool_state"`
+}
+
+// PoolChangeHandler 池子变化回调, prev 为 nil 表示新加入的池子, cur 为 nil 表示被移除;
+// 在同步协程中调用, 不要阻塞
+type PoolChangeHandler func(prev, cur *RegisteredPool)
+
+// poolIndex 按某个字段索引池子
+type poolIndex[K comparable] map[K]map[solana.PublicKey]struct{}
+
+func (idx poolIndex[K]) add(key K, poolId solana.PublicKey) {
+	pools, ok := idx[key]
+	if !ok {
+		pools = make(map[solana.PublicKey]struct{})
+		idx[key] = pools
+	}
+	pools[poolId] = struct{}{}
+}
+
+func (idx poolIndex[K]) remove(key K, poolId solana.PublicKey) {
+	if pools, ok := idx[key]; ok {
+		delete(pools, poolId)
+		if len(pools) == 0 {
+			delete(idx, key)
+		}
+	}
+}
+
+// PoolRegistry 程序的全部 PoolState
+//
+// 第一次订阅成功后通过 getProgramAccounts 加载全部池子, 之后由 programSubscribe 保持更新.
+// 账户被关闭后不再匹配订阅的过滤器, 收不到通知, 只在重新加载时移除(launchpad 程序不会关闭池子账户)
+type PoolRegistry struct {
+	Client     *Client            // 连接管理, 可以设置 ReconnectPolicy、OnStateChange
+	Commitment rpc.CommitmentType // 默认 confirmed
+
+	// 重连后重新执行 getProgramAccounts 补齐断线期间的变化, 默认只加载一次;
+	// 池子数量很多时每次加载都需要下载全部账户, 多个端点时其它端点的订阅可以覆盖断线期间的变化
+	ResyncOnReconnect bool
+
+	lock       sync.RWMutex
+	pools      map[solana.PublicKey]*RegisteredPool
+	byBaseMint poolIndex[solana.PublicKey]
+	byCreator  poolIndex[solana.PublicKey]
+	byPlatform poolIndex[solana.PublicKey]
+	byStatus   poolIndex[raydium_launchpad.PoolStatus]
+	onChange   []PoolChangeHandler
+	seedLock   sync.Mutex
+	seeded     bool
+	connected  map[*endpoint]bool // 订阅成功过的端点
+	ready      chan struct{}
+	readyOnce  sync.Once
+}
+
+// NewPoolRegistry 创建池子注册表, option 与 NewClient 相同
+func NewPoolRegistry(ctx context.Context, option ...gosolana.Option) *PoolRegistry {
+	return newPoolRegistry(NewClient(ctx, option...))
+}
+
+func newPoolRegistry(client *Client) *PoolRegistry {
+	return &PoolRegistry{
+		Client:     client,
+		Commitment: rpc.CommitmentConfirmed,
+		pools:      make(map[solana.PublicKey]*RegisteredPool),
+		byBaseMint: make(poolIndex[solana.PublicKey]),
+		byCreator:  make(poolIndex[solana.PublicKey]),
+		byPlatform: make(poolIndex[solana.PublicKey]),
+		byStatus:   make(poolIndex[raydium_launchpad.PoolStatus]),
+		connected:  make(map[*endpoint]bool),
+		ready:      make(chan struct{}),
+	}
+}
+
+// Run 开始同步, 直到 ctx 结束、Client 关闭或全部端点重连失败
+func (r *PoolRegistry) Run(ctx context.Context) error {
+	return r.Client.run(ctx, r.subscribe)
+}
+
+// Ready 第一次加载完成后关闭
+func (r *PoolRegistry) Ready() <-chan struct{} {
+	return r.ready
+}
+
+// OnChange 添加一个池子变化回调
+func (r *PoolRegistry) OnChange(handler PoolChangeHandler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.onChange = append(r.onChange, handler)
+}
+
+// Pool 按池子地址查询
+func (r *PoolRegistry) Pool(poolId solana.PublicKey) (*RegisteredPool, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	pool, ok := r.pools[poolId]
+	return pool, ok
+}
+
+// ByBaseMint 按 base mint 查询, 不同的报价代币可能对应多个池子
+func (r *PoolRegistry) ByBaseMint(baseMint solana.PublicKey) []*RegisteredPool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.collect(r.byBaseMint[baseMint])
+}
+
+// ByCreator 按创建者查询
+func (r *PoolRegistry) ByCreator(creator solana.PublicKey) []*RegisteredPool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.collect(r.byCreator[creator])
+}
+
+// ByPlatformConfig 按平台配置查询
+func (r *PoolRegistry) ByPlatformConfig(platformConfig solana.PublicKey) []*RegisteredPool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.collect(r.byPlatform[platformConfig])
+}
+
+// ByStatus 按池子状态查询
+func (r *PoolRegistry) ByStatus(status raydium_launchpad.PoolStatus) []*RegisteredPool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.collect(r.byStatus[status])
+}
+
+// Len 池子数量
+func (r *PoolRegistry) Len() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return len(r.pools)
+}
+
+// Range 遍历全部池子, fn 返回 false 时停止; 遍历的是调用时的快照, fn 中可以调用注册表的其它方法
+func (r *PoolRegistry) Range(fn func(*RegisteredPool) bool) {
+	r.lock.RLock()
+	pools := make([]*RegisteredPool, 0, len(r.pools))
+	for _, pool := range r.pools {
+		pools = append(pools, pool)
+	}
+	r.lock.RUnlock()
+
+	for _, pool := range pools {
+		if !fn(pool) {
+			return
+		}
+	}
+}
+
+func (r *PoolRegistry) collect(poolIds map[solana.PublicKey]struct{}) []*RegisteredPool {
+	result := make([]*RegisteredPool, 0, len(poolIds))
+	for poolId := range poolIds {
+		result = append(result, r.pools[poolId])
+	}
+	return result
+}
+
+// subscribe 在端点上订阅程序账户, 订阅断开时返回
+func (r *PoolRegistry) subscribe(ctx context.Context, ep *endpoint) (time.Time, error) {
+	client := r.Client.getWsClient(ep)
+	sub, err := client.ProgramSubscribeWithOpts(raydium_launchpad.ProgramID, r.Commitment, solana.EncodingBase64, PoolStateFilter())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("订阅程序账户失败: %w", err)
+	}
+	defer sub.Unsubscribe()
+	connectedAt := time.Now()
+	r.Client.setState(ep, ConnStateConnected, 0, 0, nil)
+
+	// 订阅之后再加载, 加载期间的变化会通过订阅收到
+	if err := r.seed(ctx, ep); err != nil {
+		return connectedAt, err
+	}
+
+	for {
+		msg, err := sub.Recv(ctx)
+		if err == nil && msg == nil {
+			err = ws.ErrSubscriptionClosed
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return connectedAt, ctx.Err()
+			}
+			return connectedAt, fmt.Errorf("程序账户的订阅中断: %w", err)
+		}
+		r.apply(msg.Value.Pubkey, msg.Context.Slot, msg.Value.Account)
+	}
+}
+
+// seed 通过 getProgramAccounts 加载全部池子
+func (r *PoolRegistry) seed(ctx context.Context, ep *endpoint) error {
+	r.seedLock.Lock()
+	defer r.seedLock.Unlock()
+	reconnect := r.connected[ep]
+	r.connected[ep] = true
+	if r.seeded && !(reconnect && r.ResyncOnReconnect) {
+		return nil
+	}
+
+	// 先获取 slot, 加载的数据不会覆盖订阅收到的更新的数据
+	slot, pools, err := queryPools(ctx, r.Client.rpcClient(), r.Commitment)
+	if err != nil {
+		return fmt.Errorf("加载池子失败: %w", err)
+	}
+	loaded := make(map[solana.PublicKey]bool, len(pools))
+	for _, pool := range pools {
+		loaded[pool.PoolId] = true
+		r.set(pool.PoolId, pool.Slot, pool.PoolState)
+	}
+
+	// 不在结果中、也没有在该 slot 之后收到更新的池子已经不存在
+	var closed []solana.PublicKey
+	r.lock.RLock()
+	for poolId, pool := range r.pools {
+		if !loaded[poolId] && pool.Slot < slot {
+			closed = append(closed, poolId)
+		}
+	}
+	r.lock.RUnlock()
+	for _, poolId := range closed {
+		r.set(poolId, slot, nil)
+	}
+	r.seeded = true
+	r.readyOnce.Do(func() {
+		close(r.ready)
+	})
+	log.Printf("池子注册表加载完成 | 池子: %d", len(pools))
+	return nil
+}
+
+// apply 更新一个池子, 旧的 slot 会被忽略
+func (r *PoolRegistry) apply(poolId solana.PublicKey, slot uint64, account *rpc.Account) {
+	parsed, err := raydium_launchpad.ParseAnyAccount(accountData(account))
+	if err != nil {
+		log.Printf("解析池子 %s 失败 | %v", poolId, err)
+		return
+	}
+	poolState, ok := parsed.(*raydium_launchpad.PoolState)
+	if !ok {
+		log.Printf("账户 %s 不是池子 | %T", poolId, parsed)
+		return
+	}
+	r.set(poolId, slot, poolState)
+}
+
+// set 替换池子并更新索引, poolState 为 nil 时移除
+func (r *PoolRegistry) set(poolId solana.PublicKey, slot uint64, poolState *raydium_launchpad.PoolState) {
+	r.lock.Lock()
+	prev, ok := r.pools[poolId]
+	if ok && slot < prev.Slot {
+		r.lock.Unlock()
+		return
+	}
+	if ok {
+		r.unindex(prev)
+	}
+	var pool *RegisteredPool
+	if poolState == nil {
+		delete(r.pools, poolId)
+	} else {
+		pool = &RegisteredPool{PoolId: poolId, Slot: slot, PoolState: poolState}
+		r.pools[poolId] = pool
+		r.index(pool)
+	}
+	handlers := append([]PoolChangeHandler(nil), r.onChange...)
+	r.lock.Unlock()
+
+	if prev == nil && pool == nil {
+		return
+	}
+	for _, handler := range handlers {
+		handler(prev, pool)
+	}
+}
+
+func (r *PoolRegistry) index(pool *RegisteredPool) {
+	r.byBaseMint.add(pool.PoolState.BaseMint, pool.PoolId)
+	r.byCreator.add(pool.PoolState.Creator, pool.PoolId)
+	r.byPlatform.add(pool.PoolState.PlatformConfig, pool.PoolId)
+	r.byStatus.add(raydium_launchpad.PoolStatus(pool.PoolState.Status), pool.PoolId)
+}
+
+func (r *PoolRegistry) unindex(pool *RegisteredPool) {
+	r.byBaseMint.remove(pool.PoolState.BaseMint, pool.PoolId)
+	r.byCreator.remove(pool.PoolState.Creator, pool.PoolId)
+	r.byPlatform.remove(pool.PoolState.PlatformConfig, pool.PoolId)
+	r.byStatus.remove(raydium_launchpad.PoolStatus(pool.PoolState.Status), pool.PoolId)
+}
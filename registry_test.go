@@ -0,0 +1,100 @@
+package bonk
+
+import (
+	"context"
+	"testing"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func poolAccount(t *testing.T, poolState *raydium_launchpad.PoolState) *rpc.Account {
+	t.Helper()
+	body, err := poolState.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(raydium_launchpad.Account_PoolState[:], body...)
+	return &rpc.Account{Lamports: 1, Owner: raydium_launchpad.ProgramID, Data: rpc.DataBytesOrJSONFromBytes(data)}
+}
+
+func TestPoolRegistrySeed(t *testing.T) {
+	var (
+		kept    = solana.NewWallet().PublicKey()
+		closed  = solana.NewWallet().PublicKey()
+		updated = solana.NewWallet().PublicKey()
+		broken  = solana.NewWallet().PublicKey()
+		mint    = solana.NewWallet().PublicKey()
+	)
+	accounts := []*rpc.KeyedAccount{
+		{Pubkey: kept, Account: poolAccount(t, &raydium_launchpad.PoolState{BaseMint: mint})},
+		{Pubkey: broken, Account: &rpc.Account{Lamports: 1, Data: rpc.DataBytesOrJSONFromBytes([]byte{1, 2, 3})}},
+	}
+	inner := newFakeJSONRPC(func(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+		if method == "getSlot" {
+			return 100, nil
+		}
+		return accounts, nil
+	})
+	registry := newPoolRegistry(&Client{RpcClient: rpc.NewWithCustomRPCClient(inner)})
+	var removed []solana.PublicKey
+	registry.OnChange(func(prev, cur *RegisteredPool) {
+		if cur == nil {
+			removed = append(removed, prev.PoolId)
+		}
+	})
+
+	// 加载前通过订阅收到的池子: closed 不在加载结果中, updated 在加载的 slot 之后更新过
+	registry.set(closed, 90, &raydium_launchpad.PoolState{})
+	registry.set(updated, 101, &raydium_launchpad.PoolState{})
+
+	// 无法解析的账户不影响其它池子
+	if err := registry.seed(context.Background(), &endpoint{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-registry.Ready():
+	default:
+		t.Error("Ready() not closed after seed")
+	}
+	if pool, ok := registry.Pool(kept); !ok || pool.Slot != 100 || !pool.PoolState.BaseMint.Equals(mint) {
+		t.Errorf("Pool(kept) = %+v, %v", pool, ok)
+	}
+	if len(registry.ByBaseMint(mint)) != 1 {
+		t.Errorf("ByBaseMint() = %v", registry.ByBaseMint(mint))
+	}
+	if _, ok := registry.Pool(broken); ok {
+		t.Error("undecodable account registered")
+	}
+	if _, ok := registry.Pool(closed); ok {
+		t.Error("pool missing from the query result not removed")
+	}
+	if _, ok := registry.Pool(updated); !ok {
+		t.Error("pool updated after the query slot removed")
+	}
+	if len(removed) != 1 || !removed[0].Equals(closed) {
+		t.Errorf("removed = %v, want [%s]", removed, closed)
+	}
+
+	// 无法解析的通知被忽略
+	registry.apply(kept, 200, &rpc.Account{Lamports: 1, Data: rpc.DataBytesOrJSONFromBytes(nil)})
+	if pool, _ := registry.Pool(kept); pool.Slot != 100 {
+		t.Errorf("Pool(kept).Slot = %d, want 100", pool.Slot)
+	}
+	// 其它类型的账户不是池子
+	body, err := (&raydium_launchpad.GlobalConfig{}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(raydium_launchpad.Account_GlobalConfig[:], body...)
+	registry.apply(kept, 200, &rpc.Account{Lamports: 1, Data: rpc.DataBytesOrJSONFromBytes(data)})
+	if pool, _ := registry.Pool(kept); pool.Slot != 100 {
+		t.Errorf("Pool(kept).Slot = %d after GlobalConfig, want 100", pool.Slot)
+	}
+	registry.apply(kept, 200, poolAccount(t, &raydium_launchpad.PoolState{Status: uint8(raydium_launchpad.PoolStatus_Migrate)}))
+	if pools := registry.ByStatus(raydium_launchpad.PoolStatus_Migrate); len(pools) != 1 || pools[0].Slot != 200 {
+		t.Errorf("ByStatus(Migrate) = %v", pools)
+	}
+}
@@ -0,0 +1,480 @@
+package bonk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/go-enols/gosolana"
+)
+
+const maxMultipleAccounts = 100 // getMultipleAccounts 单次最多读取的账户数量
+
+// RPCOptions 共享 RPC 的限流、重试和批量读取配置
+type RPCOptions struct {
+	RateLimit   float64         // 每秒最多请求数, 0 表示不限制
+	Burst       int             // 令牌桶容量, 默认为 RateLimit 向上取整
+	Retry       ReconnectPolicy // 读请求遇到 429 和 5xx 的退避策略, MaxFailures 为最多重试次数
+	CallTimeout time.Duration   // 合并的读请求和 getMultipleAccounts 批量读取的超时(包括重试), 默认 30s
+	BatchWindow time.Duration   // LoadAccount 合并为一次 getMultipleAccounts 的等待时间
+	BatchSize   int             // 单次 getMultipleAccounts 的账户数量上限, 最大 100
+}
+
+var DefaultRPCOptions = RPCOptions{
+	Retry: ReconnectPolicy{
+		InitialDelay: 200 * time.Millisecond,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxFailures:  5,
+	},
+	CallTimeout: 30 * time.Second,
+	BatchWindow: 5 * time.Millisecond,
+	BatchSize:   maxMultipleAccounts,
+}
+
+// AccountLoader 读取账户, 不存在的账户为 nil; 多个组件使用同一个 *RPC 时读取会被合并
+type AccountLoader interface {
+	LoadAccounts(ctx context.Context, keys ...solana.PublicKey) ([]*rpc.Account, error)
+}
+
+// RPCStats 共享 RPC 的统计
+type RPCStats struct {
+	Requests        uint64 `json:"requests"`         // 实际发出的请求数量, 包括重试
+	Retries         uint64 `json:"retries"`          // 重试次数
+	Throttled       uint64 `json:"throttled"`        // 收到 429 的次数
+	Coalesced       uint64 `json:"coalesced"`        // 与相同的并发请求合并, 没有单独发出的请求数量
+	Batches         uint64 `json:"batches"`          // LoadAccount 合并后的 getMultipleAccounts 请求数量
+	BatchedAccounts uint64 `json:"batched_accounts"` // 通过合并读取的账户数量
+}
+
+// RPC 共享的 RPC 访问层, PoolMonit、Trader 等组件使用同一个实例统一限流
+//
+// 内嵌的 rpc.Client 的全部请求都会经过令牌桶限流, 读请求遇到 429 和 5xx 会按退避策略重试, 并发的相同读请求只发出一次;
+// 发送交易等有副作用的请求不重试也不合并
+type RPC struct {
+	*rpc.Client
+
+	endpoint string
+	inner    rpc.JSONRPCClient
+	options  RPCOptions
+	limiter  *tokenBucket
+
+	callLock sync.Mutex
+	calls    map[string]*rpcCall // 正在进行的读请求
+
+	batchLock sync.Mutex
+	pending   []*accountRequest // 等待合并读取的账户
+
+	requests        atomic.Uint64
+	retries         atomic.Uint64
+	throttled       atomic.Uint64
+	coalesced       atomic.Uint64
+	batches         atomic.Uint64
+	batchedAccounts atomic.Uint64
+}
+
+// NewRPC 创建共享的 RPC 访问层, 使用 option 中的 RpcUrl、Headers、HTTPClient、Proxy 和 TimeOut
+func NewRPC(option gosolana.Option, options ...RPCOptions) (*RPC, error) {
+	endpoint := option.RpcUrl
+	if endpoint == "" {
+		endpoint = rpc.DevNet_RPC
+	}
+	httpClient := option.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+		if option.Proxy != "" {
+			client, err := gosolana.NewProxyHttpClient(option.Proxy)
+			if err != nil {
+				return nil, err
+			}
+			httpClient = client
+		}
+		httpClient.Timeout = 5 * time.Second
+	}
+	if option.TimeOut > 0 {
+		httpClient.Timeout = option.TimeOut
+	}
+	inner := jsonrpc.NewClientWithOpts(endpoint, &jsonrpc.RPCClientOpts{
+		HTTPClient:    httpClient,
+		CustomHeaders: option.Headers,
+	})
+	return newRPC(endpoint, inner, options...), nil
+}
+
+func newRPC(endpoint string, inner rpc.JSONRPCClient, options ...RPCOptions) *RPC {
+	opts := DefaultRPCOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.BatchSize <= 0 || opts.BatchSize > maxMultipleAccounts {
+		opts.BatchSize = maxMultipleAccounts
+	}
+	if opts.CallTimeout <= 0 {
+		opts.CallTimeout = DefaultRPCOptions.CallTimeout
+	}
+	r := &RPC{
+		endpoint: endpoint,
+		inner:    inner,
+		options:  opts,
+		calls:    make(map[string]*rpcCall),
+	}
+	if opts.RateLimit > 0 {
+		r.limiter = newTokenBucket(opts.RateLimit, opts.Burst)
+	}
+	r.Client = rpc.NewWithCustomRPCClient(&rpcTransport{r})
+	return r
+}
+
+// Option 将共享的 rpc 客户端设置到 option 中, 用于创建 PoolMonit、Trader、Client 等组件;
+// 组件的账户读取需要再将 RPC 设置到组件的 Accounts 才会与其他组件合并
+func (r *RPC) Option(option gosolana.Option) gosolana.Option {
+	option.RpcUrl = r.endpoint
+	option.RpcClient = r.Client
+	if client, ok := r.inner.(jsonrpc.RPCClient); ok {
+		option.JsonRpcClient = client
+	}
+	return option
+}
+
+// Stats 获取请求、重试、合并和批量读取的统计
+func (r *RPC) Stats() RPCStats {
+	return RPCStats{
+		Requests:        r.requests.Load(),
+		Retries:         r.retries.Load(),
+		Throttled:       r.throttled.Load(),
+		Coalesced:       r.coalesced.Load(),
+		Batches:         r.batches.Load(),
+		BatchedAccounts: r.batchedAccounts.Load(),
+	}
+}
+
+// do 限流后发出请求, idempotent 为 true 时 429 和 5xx 按退避策略重试
+//
+// 有副作用的请求收到 5xx 时服务端可能已经执行, 重试可能重复发送交易, 只返回错误
+func (r *RPC) do(ctx context.Context, idempotent bool, call func() error) error {
+	policy := r.options.Retry.withDefaults()
+	for attempt := 1; ; attempt++ {
+		if r.limiter != nil {
+			if err := r.limiter.wait(ctx); err != nil {
+				return err
+			}
+		}
+		r.requests.Add(1)
+		err := call()
+		if err == nil || !r.retryable(err) || !idempotent || policy.exhausted(attempt) {
+			return err
+		}
+		r.retries.Add(1)
+
+		timer := time.NewTimer(policy.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable 是否为 429 或 5xx
+func (r *RPC) retryable(err error) bool {
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Code == http.StatusTooManyRequests {
+			r.throttled.Add(1)
+			return true
+		}
+		return httpErr.Code >= http.StatusInternalServerError
+	}
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == http.StatusTooManyRequests {
+		r.throttled.Add(1)
+		return true
+	}
+	return false
+}
+
+// rpcCall 正在进行的读请求, 相同的并发请求共享结果
+type rpcCall struct {
+	done   chan struct{}
+	result json.RawMessage
+	err    error
+}
+
+// coalesce 合并相同的并发请求, 返回原始结果
+//
+// 共享的请求在单独的协程中执行, 使用不会被取消的 ctx 和 CallTimeout, 任一调用方放弃等待都不影响其他调用方
+func (r *RPC) coalesce(ctx context.Context, key string, call func(ctx context.Context) (json.RawMessage, error)) (json.RawMessage, error) {
+	r.callLock.Lock()
+	c, ok := r.calls[key]
+	if ok {
+		r.coalesced.Add(1)
+	} else {
+		c = &rpcCall{done: make(chan struct{})}
+		r.calls[key] = c
+		callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.options.CallTimeout)
+		go func() {
+			defer cancel()
+			c.result, c.err = call(callCtx)
+			r.callLock.Lock()
+			delete(r.calls, key)
+			r.callLock.Unlock()
+			close(c.done)
+		}()
+	}
+	r.callLock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return c.result, c.err
+	}
+}
+
+// idempotent 是否为没有副作用的请求, 只有这些请求会被重试和合并
+func idempotent(method string) bool {
+	switch method {
+	case "sendTransaction", "requestAirdrop":
+		return false
+	default:
+		return true
+	}
+}
+
+// rpcTransport 实现 rpc.JSONRPCClient, 所有请求经过 RPC 的限流、重试和合并
+type rpcTransport struct {
+	r *RPC
+}
+
+func (t *rpcTransport) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	r := t.r
+	if idempotent(method) {
+		if key, err := json.Marshal(params); err == nil {
+			result, err := r.coalesce(ctx, method+string(key), func(ctx context.Context) (json.RawMessage, error) {
+				var result json.RawMessage
+				err := r.do(ctx, true, func() error {
+					return r.inner.CallForInto(ctx, &result, method, params)
+				})
+				return result, err
+			})
+			if err != nil {
+				return err
+			}
+			if result == nil {
+				result = json.RawMessage("null")
+			}
+			return json.Unmarshal(result, out)
+		}
+	}
+	return r.do(ctx, idempotent(method), func() error {
+		return r.inner.CallForInto(ctx, out, method, params)
+	})
+}
+
+func (t *rpcTransport) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return t.r.do(ctx, idempotent(method), func() error {
+		return t.r.inner.CallWithCallback(ctx, method, params, callback)
+	})
+}
+
+func (t *rpcTransport) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	retry := true
+	for _, request := range requests {
+		retry = retry && idempotent(request.Method)
+	}
+	var responses jsonrpc.RPCResponses
+	err := t.r.do(ctx, retry, func() (err error) {
+		responses, err = t.r.inner.CallBatch(ctx, requests)
+		return err
+	})
+	return responses, err
+}
+
+// tokenBucket 令牌桶限流, 令牌不足时预支并等待, 按请求顺序放行
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64 // 每秒生成的令牌数
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait 取得一个令牌, ctx 结束时返回错误
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.lock.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.lock.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// accountRequest 等待合并读取的账户
+type accountRequest struct {
+	key     solana.PublicKey
+	done    chan struct{}
+	account *rpc.Account
+	err     error
+}
+
+// LoadAccount 读取单个账户, 与 BatchWindow 内其他组件的读取合并为一次 getMultipleAccounts
+//
+// 账户不存在时返回 rpc.ErrNotFound
+func (r *RPC) LoadAccount(ctx context.Context, key solana.PublicKey) (*rpc.Account, error) {
+	req := &accountRequest{key: key, done: make(chan struct{})}
+	r.batchLock.Lock()
+	r.pending = append(r.pending, req)
+	switch {
+	case len(r.pending) >= r.options.BatchSize:
+		batch := r.pending
+		r.pending = nil
+		go r.flush(batch)
+	case len(r.pending) == 1:
+		time.AfterFunc(r.options.BatchWindow, r.flushPending)
+	}
+	r.batchLock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-req.done:
+	}
+	if req.err != nil {
+		return nil, req.err
+	}
+	if req.account == nil {
+		return nil, rpc.ErrNotFound
+	}
+	return req.account, nil
+}
+
+// LoadAccounts 读取多个账户, 不存在的账户为 nil
+//
+// 不超过 BatchSize 时与其他组件的读取合并, 超过时分多次请求
+func (r *RPC) LoadAccounts(ctx context.Context, keys ...solana.PublicKey) ([]*rpc.Account, error) {
+	if len(keys) > r.options.BatchSize {
+		return getMultipleAccounts(ctx, r.Client, r.options.BatchSize, keys...)
+	}
+	accounts := make([]*rpc.Account, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key solana.PublicKey) {
+			defer wg.Done()
+			accounts[i], errs[i] = r.LoadAccount(ctx, key)
+			if errors.Is(errs[i], rpc.ErrNotFound) {
+				errs[i] = nil
+			}
+		}(i, key)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *RPC) flushPending() {
+	r.batchLock.Lock()
+	batch := r.pending
+	r.pending = nil
+	r.batchLock.Unlock()
+	if len(batch) > 0 {
+		r.flush(batch)
+	}
+}
+
+// flush 合并读取一批账户, 重复的账户只读取一次
+func (r *RPC) flush(batch []*accountRequest) {
+	index := make(map[solana.PublicKey]int, len(batch))
+	keys := make([]solana.PublicKey, 0, len(batch))
+	for _, req := range batch {
+		if _, ok := index[req.key]; !ok {
+			index[req.key] = len(keys)
+			keys = append(keys, req.key)
+		}
+	}
+	r.batches.Add(1)
+	r.batchedAccounts.Add(uint64(len(batch)))
+
+	// 请求方可能已经放弃等待, 这里不使用请求方的 ctx
+	ctx, cancel := context.WithTimeout(context.Background(), r.options.CallTimeout)
+	defer cancel()
+	accounts, err := r.GetMultipleAccounts(ctx, keys...)
+	for _, req := range batch {
+		switch {
+		case err != nil:
+			req.err = fmt.Errorf("读取账户失败: %w", err)
+		case index[req.key] < len(accounts.Value):
+			req.account = accounts.Value[index[req.key]]
+		}
+		close(req.done)
+	}
+}
+
+// loadAccounts 读取多个账户, 不存在的账户为 nil; loader 为 nil 时直接使用 client 读取
+func loadAccounts(ctx context.Context, loader AccountLoader, client *rpc.Client, keys ...solana.PublicKey) ([]*rpc.Account, error) {
+	if loader != nil {
+		return loader.LoadAccounts(ctx, keys...)
+	}
+	return getMultipleAccounts(ctx, client, maxMultipleAccounts, keys...)
+}
+
+// rpcClient loader 为共享的 *RPC 时使用它的 rpc 客户端, 限流和重试与其他组件一致, 否则使用组件自己的 client
+func rpcClient(loader AccountLoader, client *rpc.Client) *rpc.Client {
+	if r, ok := loader.(*RPC); ok && r != nil {
+		return r.Client
+	}
+	return client
+}
+
+// getMultipleAccounts 每次最多读取 size 个账户
+func getMultipleAccounts(ctx context.Context, client *rpc.Client, size int, keys ...solana.PublicKey) ([]*rpc.Account, error) {
+	result := make([]*rpc.Account, 0, len(keys))
+	for start := 0; start < len(keys); start += size {
+		end := min(start+size, len(keys))
+		accounts, err := client.GetMultipleAccounts(ctx, keys[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("读取账户失败: %w", err)
+		}
+		result = append(result, accounts.Value...)
+	}
+	return result, nil
+}
@@ -0,0 +1,243 @@
+package bonk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// fakeJSONRPC 按方法名返回结果的 rpc.JSONRPCClient
+type fakeJSONRPC struct {
+	lock    sync.Mutex
+	calls   map[string]int
+	handler func(ctx context.Context, method string, params []interface{}) (interface{}, error)
+}
+
+func newFakeJSONRPC(handler func(ctx context.Context, method string, params []interface{}) (interface{}, error)) *fakeJSONRPC {
+	return &fakeJSONRPC{calls: make(map[string]int), handler: handler}
+}
+
+func (f *fakeJSONRPC) count(method string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.calls[method]
+}
+
+func (f *fakeJSONRPC) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	f.lock.Lock()
+	f.calls[method]++
+	f.lock.Unlock()
+	result, err := f.handler(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (f *fakeJSONRPC) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeJSONRPC) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	return nil, errors.New("not implemented")
+}
+
+var testRetry = ReconnectPolicy{InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxFailures: 3}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(50, 2)
+	start := time.Now()
+	for range 3 {
+		if err := bucket.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 前两个令牌立即取得, 第三个需要等待 1/50 秒
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("elapsed = %v, want >= 20ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bucket.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() = %v, want context.Canceled", err)
+	}
+}
+
+func TestRPCRetry(t *testing.T) {
+	failures := map[string]int{"getBalance": 1, "sendTransaction": 1}
+	var lock sync.Mutex
+	inner := newFakeJSONRPC(func(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures[method] > 0 {
+			failures[method]--
+			return nil, jsonrpc.NewHTTPError(http.StatusServiceUnavailable, errors.New("unavailable"))
+		}
+		switch method {
+		case "getBalance":
+			return rpc.GetBalanceResult{Value: 42}, nil
+		default:
+			return solana.Signature{}.String(), nil
+		}
+	})
+	shared := newRPC("", inner, RPCOptions{Retry: testRetry})
+
+	balance, err := shared.GetBalance(context.Background(), solana.SolMint, rpc.CommitmentConfirmed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Value != 42 || inner.count("getBalance") != 2 {
+		t.Errorf("balance = %d, calls = %d, want 42, 2", balance.Value, inner.count("getBalance"))
+	}
+
+	// 发送交易收到 5xx 时可能已经被执行, 不重试
+	if _, err := shared.SendRawTransaction(context.Background(), []byte{1}); err == nil {
+		t.Error("SendRawTransaction() error = nil")
+	}
+	if calls := inner.count("sendTransaction"); calls != 1 {
+		t.Errorf("sendTransaction calls = %d, want 1", calls)
+	}
+	if stats := shared.Stats(); stats.Retries != 1 || stats.Requests != 3 {
+		t.Errorf("stats = %+v, want 1 retry, 3 requests", stats)
+	}
+}
+
+func TestRPCCoalesce(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	inner := newFakeJSONRPC(func(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		// 第一个调用方放弃等待不影响共享的请求
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return rpc.GetBalanceResult{Value: 42}, nil
+	})
+	shared := newRPC("", inner, RPCOptions{Retry: testRetry})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := shared.GetBalance(ctx, solana.SolMint, rpc.CommitmentConfirmed)
+		first <- err
+	}()
+	<-started
+
+	const waiters = 4
+	var wg sync.WaitGroup
+	results := make([]uint64, waiters)
+	errs := make([]error, waiters)
+	for i := range waiters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			balance, err := shared.GetBalance(context.Background(), solana.SolMint, rpc.CommitmentConfirmed)
+			if err == nil {
+				results[i] = balance.Value
+			}
+			errs[i] = err
+		}(i)
+	}
+	for shared.Stats().Coalesced < waiters {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("first caller error = %v, want context.Canceled", err)
+	}
+	close(release)
+	wg.Wait()
+
+	for i := range waiters {
+		if errs[i] != nil || results[i] != 42 {
+			t.Errorf("waiter %d = %d, %v, want 42", i, results[i], errs[i])
+		}
+	}
+	if calls := inner.count("getBalance"); calls != 1 {
+		t.Errorf("getBalance calls = %d, want 1", calls)
+	}
+}
+
+func TestRPCLoadAccounts(t *testing.T) {
+	keys := []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+	missing := solana.NewWallet().PublicKey()
+	lamports := map[solana.PublicKey]uint64{keys[0]: 1, keys[1]: 2, keys[2]: 3}
+
+	var lock sync.Mutex
+	var requested [][]solana.PublicKey
+	inner := newFakeJSONRPC(func(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+		requestKeys := params[0].([]solana.PublicKey)
+		lock.Lock()
+		requested = append(requested, requestKeys)
+		lock.Unlock()
+		result := rpc.GetMultipleAccountsResult{}
+		for _, key := range requestKeys {
+			var account *rpc.Account
+			if value, ok := lamports[key]; ok {
+				account = &rpc.Account{Lamports: value, Data: rpc.DataBytesOrJSONFromBytes(nil)}
+			}
+			result.Value = append(result.Value, account)
+		}
+		return result, nil
+	})
+	shared := newRPC("", inner, RPCOptions{Retry: testRetry, BatchWindow: 20 * time.Millisecond, BatchSize: 4})
+
+	// 并发的读取在 BatchWindow 内合并, 重复的账户只读取一次
+	var wg sync.WaitGroup
+	var batchErr, singleErr error
+	var batch []*rpc.Account
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		batch, batchErr = shared.LoadAccounts(context.Background(), keys[0], missing, keys[1])
+	}()
+	go func() {
+		defer wg.Done()
+		_, singleErr = shared.LoadAccount(context.Background(), keys[0])
+	}()
+	wg.Wait()
+	if batchErr != nil || singleErr != nil {
+		t.Fatal(batchErr, singleErr)
+	}
+	if len(batch) != 3 || batch[0].Lamports != 1 || batch[1] != nil || batch[2].Lamports != 2 {
+		t.Errorf("LoadAccounts() = %v", batch)
+	}
+	if len(requested) != 1 || len(requested[0]) != 3 {
+		t.Errorf("requests = %v, want one request with 3 accounts", requested)
+	}
+	if stats := shared.Stats(); stats.Batches != 1 || stats.BatchedAccounts != 4 {
+		t.Errorf("stats = %+v, want 1 batch, 4 accounts", stats)
+	}
+
+	if _, err := shared.LoadAccount(context.Background(), missing); !errors.Is(err, rpc.ErrNotFound) {
+		t.Errorf("LoadAccount(missing) = %v, want rpc.ErrNotFound", err)
+	}
+
+	// 超过 BatchSize 时分多次请求
+	requested = nil
+	accounts, err := shared.LoadAccounts(context.Background(), append(keys, missing, keys[0])...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 5 || accounts[2].Lamports != 3 || accounts[3] != nil || accounts[4].Lamports != 1 {
+		t.Errorf("LoadAccounts() = %v", accounts)
+	}
+	if len(requested) != 2 {
+		t.Errorf("requests = %d, want 2", len(requested))
+	}
+}
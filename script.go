@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	raydium_launchpad "github.com/go-enols/go-bonk/idl"
@@ -20,6 +21,8 @@ import (
 
 var Verison uint64 = 1
 
+var ErrPoolMonitClosed = errors.New("PoolMonit 已关闭")
+
 // InitializeTransactionData Initialize交易解析后的汇总数据
 type InitializeAccounts struct {
 	Payer             solana.PublicKey                  `json:"payer"`
@@ -47,12 +50,26 @@ type InitializeTransactionData struct {
 	Discriminator string                          `json:"discriminator"`
 	DataLength    int                             `json:"data_length"`
 	AccountCount  int                             `json:"account_count"`
-	MintParams    raydium_launchpad.MintParams    `json:"mint_params"` // 代币的元数据
-	CurveParams   raydium_launchpad.CurveParams   `json:"curve_params"`
+	MintParams    raydium_launchpad.MintParams    `json:"mint_params"`    // 代币的元数据
+	CurveType     CurveType                       `json:"curve_type"`     // 曲线类型, 决定 CurveParams 的具体变体
+	CurveParams   raydium_launchpad.CurveParams   `json:"curve_params"`   // *CurveParams_Constant / *CurveParams_Fixed / *CurveParams_Linear
 	VestingParams raydium_launchpad.VestingParams `json:"vesting_params"` // 包含解锁时间总供应量等信息
 	Accounts      InitializeAccounts              `json:"accounts"`
 	RawAccounts   map[string]string               `json:"raw_accounts"`
 	TransferTime  time.Time                       `json:"transfer_time"`
+
+	InstructionIndex int    `json:"instruction_index"` // 外层指令索引
+	InnerIndex       int    `json:"inner_index"`       // inner instruction 索引, 非CPI时为 -1
+	Depth            int    `json:"depth"`             // 调用深度, 外层指令为 1, 0 表示未知
+	OuterProgram     string `json:"outer_program"`     // 外层指令调用的程序, 例如聚合器路由
+}
+
+// setInstructionRef 记录Initialize指令在交易中的位置
+func (d *InitializeTransactionData) setInstructionRef(ref *instructionRef) {
+	d.InstructionIndex = ref.Index
+	d.InnerIndex = ref.InnerIndex
+	d.Depth = ref.Depth
+	d.OuterProgram = ref.OuterProgram.String()
 }
 
 // PoolMonit Initialize交易监听器
@@ -60,6 +77,20 @@ type PoolMonit struct {
 	*gosolana.Wallet
 	ctx context.Context
 	Pip chan *InitializeTransactionData
+
+	lookupTables map[solana.PublicKey]solana.PublicKeySlice // 地址查找表缓存
+	lookupLock   sync.RWMutex
+
+	Commitment         rpc.CommitmentType // 获取交易的提交级别, 默认 confirmed
+	FallbackCommitment rpc.CommitmentType // 交易在 Commitment 下重试用尽后改用的提交级别, 默认为空即不回退; 只在节点只从长期存储(仅 finalized)查询旧交易时有用
+	TransactionRetry   ReconnectPolicy    // 交易尚不存在时的重试策略, MaxFailures 为每个提交级别最多尝试的次数
+	Accounts           AccountLoader      // 读取账户, 设置为共享的 *RPC 时与其他组件的读取合并, 为 nil 时直接请求
+	txStats            transactionStats
+
+	closeLock sync.RWMutex
+	closed    bool
+	sending   sync.WaitGroup // 正在写入 Pip 的数量
+	abort     chan struct{}  // 关闭超时后放弃未读取的数据
 }
 
 func NewPoolMonit(ctx context.Context, option ...gosolana.Option) (*PoolMonit, error) {
@@ -71,6 +102,12 @@ func NewPoolMonit(ctx context.Context, option ...gosolana.Option) (*PoolMonit, e
 		Wallet: wallet,
 		ctx:    ctx,
 		Pip:    make(chan *InitializeTransactionData),
+
+		Commitment:       rpc.CommitmentConfirmed,
+		TransactionRetry: DefaultTransactionRetry,
+
+		lookupTables: make(map[solana.PublicKey]solana.PublicKeySlice),
+		abort:        make(chan struct{}),
 	}, nil
 }
 
@@ -87,27 +124,57 @@ func (p *PoolMonit) containsInitializeInstruction(logs []string) bool {
 	return false
 }
 
-// processTransaction 处理单个交易
-func (p *PoolMonit) ProcessTransaction(signature solana.Signature) (*InitializeTransactionData, error) {
-	// 获取完整交易信息
-	transaction, err := p.GetClient().GetTransaction(p.ctx, signature, &rpc.GetTransactionOpts{
-		Commitment:                     rpc.CommitmentConfirmed,
-		MaxSupportedTransactionVersion: &Verison,
-	})
+// getTransaction 获取完整交易信息并解析出交易本体
+func (p *PoolMonit) getTransaction(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, *solana.Transaction, error) {
+	// 日志通知往往早于交易可以被获取, 不存在时按 TransactionRetry 重试
+	fetcher := &transactionFetcher{
+		client:     rpcClient(p.Accounts, p.GetClient()),
+		commitment: p.Commitment,
+		fallback:   p.FallbackCommitment,
+		policy:     p.TransactionRetry,
+		stats:      &p.txStats,
+	}
+	transaction, err := fetcher.fetch(ctx, signature)
 	if err != nil {
-		return nil, fmt.Errorf("获取交易失败: %w", err)
+		return nil, nil, err
 	}
 
 	transactionInfo, err := transaction.Transaction.GetTransaction()
 	if err != nil {
-		return nil, fmt.Errorf("解析交易失败: %w", err)
+		return nil, nil, fmt.Errorf("解析交易失败: %w", err)
 	}
 
-	// 检查交易中的指令
-	for i, instruction := range transactionInfo.Message.Instructions {
-		if p.isInitializeInstruction(instruction, transactionInfo) {
-			log.Info(fmt.Sprintf("发现Initialize交易! 签名: %s, 指令索引: %d", signature, i))
-			txData := p.handleInitializeInstruction(signature, instruction, transactionInfo)
+	// v0交易需要先解析地址查找表, 否则查找表中的账户会丢失
+	if err := p.resolveAddressTables(ctx, transactionInfo, transaction.Meta); err != nil {
+		return nil, nil, err
+	}
+	return transaction, transactionInfo, nil
+}
+
+// TransactionStats 获取交易的统计, 包括需要重试和回退提交级别的次数
+func (p *PoolMonit) TransactionStats() TransactionStats {
+	return p.txStats.snapshot()
+}
+
+// ProcessTransaction 处理单个交易, 使用创建 PoolMonit 时的 ctx
+func (p *PoolMonit) ProcessTransaction(signature solana.Signature) (*InitializeTransactionData, error) {
+	return p.ProcessTransactionContext(p.ctx, signature)
+}
+
+// ProcessTransactionContext 处理单个交易, 获取交易、重试和读取账户都在 ctx 结束时停止
+func (p *PoolMonit) ProcessTransactionContext(ctx context.Context, signature solana.Signature) (*InitializeTransactionData, error) {
+	// 获取完整交易信息
+	transaction, transactionInfo, err := p.getTransaction(ctx, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查交易中的指令, 包括通过CPI调用的指令
+	for _, ref := range flattenInstructions(transactionInfo, transaction.Meta) {
+		if p.isInitializeInstruction(ref.Instruction, transactionInfo) {
+			log.Info(fmt.Sprintf("发现Initialize交易! 签名: %s, 指令索引: %d, CPI深度: %d", signature, ref.Index, ref.Depth))
+			txData := p.handleInitializeInstruction(ctx, signature, ref.Instruction, transactionInfo)
+			txData.setInstructionRef(ref)
 			txData.TransferTime = transaction.BlockTime.Time()
 			return txData, nil
 		}
@@ -138,7 +205,7 @@ func (p *PoolMonit) isInitializeInstruction(instruction solana.CompiledInstructi
 }
 
 // handleInitializeInstruction 处理Initialize指令
-func (p *PoolMonit) handleInitializeInstruction(signature solana.Signature, instruction solana.CompiledInstruction, transaction *solana.Transaction) *InitializeTransactionData {
+func (p *PoolMonit) handleInitializeInstruction(ctx context.Context, signature solana.Signature, instruction solana.CompiledInstruction, transaction *solana.Transaction) *InitializeTransactionData {
 	data := &InitializeTransactionData{
 		Signature:     signature.String(),
 		Discriminator: hex.EncodeToString(instruction.Data[:8]),
@@ -155,7 +222,7 @@ func (p *PoolMonit) handleInitializeInstruction(signature solana.Signature, inst
 	}
 
 	// 解析账户信息
-	if err := p.parseInitializeAccounts(instruction, transaction, data); err != nil {
+	if err := p.parseInitializeAccounts(ctx, instruction, transaction, data); err != nil {
 		log.Error("解析账户信息失败:", err)
 	}
 
@@ -181,10 +248,17 @@ func (p *PoolMonit) parseInitializeParams(rawData []byte, txData *InitializeTran
 		return fmt.Errorf("解析MintParams失败: %w", err)
 	}
 
-	// curveParamParam CurveParams
-	if err := decoder.Decode(&txData.CurveParams); err != nil {
+	// curveParamParam CurveParams 是枚举, 需要先读取变体索引再解析对应的数据
+	curveParams, err := raydium_launchpad.DecodeCurveParams(decoder)
+	if err != nil {
 		return fmt.Errorf("解析CurveParams失败: %w", err)
 	}
+	curveType, err := GetCurveType(curveParams)
+	if err != nil {
+		return err
+	}
+	txData.CurveParams = curveParams
+	txData.CurveType = curveType
 
 	// vestingParamParam VestingParams
 	if err := decoder.Decode(&txData.VestingParams); err != nil {
@@ -195,42 +269,46 @@ func (p *PoolMonit) parseInitializeParams(rawData []byte, txData *InitializeTran
 }
 
 // fetchAccountData 获取并解析账户数据
-func (p *PoolMonit) fetchAccountData(txData *InitializeTransactionData) error {
-	// 获取GlobalConfig账户数据
-	globalConfigKey := txData.RawAccounts["global_config"]
-	if globalConfigKey != "" {
-		if globalConfigPubkey, err := solana.PublicKeyFromBase58(globalConfigKey); err == nil {
-			globalConfigData, err := p.GetClient().GetAccountInfo(p.ctx, globalConfigPubkey)
-			if err == nil && globalConfigData != nil && globalConfigData.Value != nil {
-				if globalConfig, err := raydium_launchpad.ParseAccount_GlobalConfig(globalConfigData.Value.Data.GetBinary()); err == nil {
-					txData.Accounts.GlobalConfig = globalConfig
-				}
-			}
+//
+// GlobalConfig、PlatformConfig、PoolState 合并为一次 getMultipleAccounts, 读取或解析失败的账户保持为空
+func (p *PoolMonit) fetchAccountData(ctx context.Context, txData *InitializeTransactionData) error {
+	names := []string{"global_config", "platform_config", "pool_state"}
+	var keys []solana.PublicKey
+	var found []string
+	for _, name := range names {
+		if key, err := solana.PublicKeyFromBase58(txData.RawAccounts[name]); err == nil {
+			keys = append(keys, key)
+			found = append(found, name)
 		}
 	}
-
-	// 获取PlatformConfig账户数据
-	platformConfigKey := txData.RawAccounts["platform_config"]
-	if platformConfigKey != "" {
-		if platformConfigPubkey, err := solana.PublicKeyFromBase58(platformConfigKey); err == nil {
-			platformConfigData, err := p.GetClient().GetAccountInfo(p.ctx, platformConfigPubkey)
-			if err == nil && platformConfigData != nil && platformConfigData.Value != nil {
-				if platformConfig, err := raydium_launchpad.ParseAccount_PlatformConfig(platformConfigData.Value.Data.GetBinary()); err == nil {
-					txData.Accounts.PlatformConfig = platformConfig
-				}
-			}
-		}
+	if len(keys) == 0 {
+		return nil
 	}
 
-	// 获取PoolState账户数据
-	poolStateKey := txData.RawAccounts["pool_state"]
-	if poolStateKey != "" {
-		if poolStatePubkey, err := solana.PublicKeyFromBase58(poolStateKey); err == nil {
-			poolStateData, err := p.GetClient().GetAccountInfo(p.ctx, poolStatePubkey)
-			if err == nil && poolStateData != nil && poolStateData.Value != nil {
-				if poolState, err := raydium_launchpad.ParseAccount_PoolState(poolStateData.Value.Data.GetBinary()); err == nil {
-					txData.Accounts.PoolState = poolState
-				}
+	accounts, err := loadAccounts(ctx, p.Accounts, p.GetClient(), keys...)
+	if err != nil {
+		return err
+	}
+	for i, account := range accounts {
+		if account == nil {
+			continue
+		}
+		data := account.Data.GetBinary()
+		switch found[i] {
+		case "global_config":
+			// 获取GlobalConfig账户数据
+			if globalConfig, err := raydium_launchpad.ParseAccount_GlobalConfig(data); err == nil {
+				txData.Accounts.GlobalConfig = globalConfig
+			}
+		case "platform_config":
+			// 获取PlatformConfig账户数据
+			if platformConfig, err := raydium_launchpad.ParseAccount_PlatformConfig(data); err == nil {
+				txData.Accounts.PlatformConfig = platformConfig
+			}
+		case "pool_state":
+			// 获取PoolState账户数据
+			if poolState, err := raydium_launchpad.ParseAccount_PoolState(data); err == nil {
+				txData.Accounts.PoolState = poolState
 			}
 		}
 	}
@@ -239,7 +317,7 @@ func (p *PoolMonit) fetchAccountData(txData *InitializeTransactionData) error {
 }
 
 // parseInitializeAccounts 解析Initialize指令的账户信息
-func (p *PoolMonit) parseInitializeAccounts(instruction solana.CompiledInstruction, transaction *solana.Transaction, txData *InitializeTransactionData) error {
+func (p *PoolMonit) parseInitializeAccounts(ctx context.Context, instruction solana.CompiledInstruction, transaction *solana.Transaction, txData *InitializeTransactionData) error {
 	// Initialize指令的账户顺序（根据生成的代码）:
 	accountNames := []string{
 		"payer",               // 0
@@ -300,7 +378,7 @@ func (p *PoolMonit) parseInitializeAccounts(instruction solana.CompiledInstructi
 		txData.Accounts.Program = accountMetas[17].PublicKey
 
 		// 尝试获取并解析账户数据
-		if err := p.fetchAccountData(txData); err != nil {
+		if err := p.fetchAccountData(ctx, txData); err != nil {
 			log.Error("获取账户数据失败:", err)
 		}
 	}
@@ -311,23 +389,17 @@ func (p *PoolMonit) parseInitializeAccounts(instruction solana.CompiledInstructi
 // GetInitializeTransactionData 获取Initialize交易的解析数据
 func (p *PoolMonit) GetInitializeTransactionData(signature solana.Signature) (*InitializeTransactionData, error) {
 	// 获取完整交易信息
-	transaction, err := p.GetClient().GetTransaction(p.ctx, signature, &rpc.GetTransactionOpts{
-		Commitment:                     rpc.CommitmentConfirmed,
-		MaxSupportedTransactionVersion: &Verison,
-	})
+	transaction, transactionInfo, err := p.getTransaction(p.ctx, signature)
 	if err != nil {
-		return nil, fmt.Errorf("获取交易失败: %w", err)
-	}
-
-	transactionInfo, err := transaction.Transaction.GetTransaction()
-	if err != nil {
-		return nil, fmt.Errorf("解析交易失败: %w", err)
+		return nil, err
 	}
 
-	// 检查交易中的指令
-	for _, instruction := range transactionInfo.Message.Instructions {
-		if p.isInitializeInstruction(instruction, transactionInfo) {
-			return p.handleInitializeInstruction(signature, instruction, transactionInfo), nil
+	// 检查交易中的指令, 包括通过CPI调用的指令
+	for _, ref := range flattenInstructions(transactionInfo, transaction.Meta) {
+		if p.isInitializeInstruction(ref.Instruction, transactionInfo) {
+			txData := p.handleInitializeInstruction(p.ctx, signature, ref.Instruction, transactionInfo)
+			txData.setInstructionRef(ref)
+			return txData, nil
 		}
 	}
 
@@ -336,18 +408,67 @@ func (p *PoolMonit) GetInitializeTransactionData(signature solana.Signature) (*I
 
 // ProcessTransaction 处理WebSocket接收到的日志结果
 func (p *PoolMonit) ProcessTransactionLogs(logResult *ws.LogResult) {
+	if err := p.HandleLog(p.ctx, logResult); err != nil {
+		log.Error("处理交易失败:", err)
+	}
+}
+
+// HandleLog 实现 LogHandler, 解析 Initialize 交易后写入 Pip
+//
+// 会一直等待 Pip 被读取, 直到 ctx 结束或 Close 超时
+func (p *PoolMonit) HandleLog(ctx context.Context, logResult *ws.LogResult) error {
 	if !p.containsInitializeInstruction(logResult.Value.Logs) {
 		// 不是需要的交易直接抛弃
-		return
+		return nil
 	}
-	data, err := p.ProcessTransaction(logResult.Value.Signature)
+	data, err := p.ProcessTransactionContext(ctx, logResult.Value.Signature)
 	if err != nil {
-		log.Error("处理交易失败:", err)
-		return
+		return err
+	}
+	return p.emit(ctx, data)
+}
+
+// emit 写入 Pip, 关闭后不再写入
+func (p *PoolMonit) emit(ctx context.Context, data *InitializeTransactionData) error {
+	p.closeLock.RLock()
+	if p.closed {
+		p.closeLock.RUnlock()
+		return ErrPoolMonitClosed
 	}
+	p.sending.Add(1)
+	p.closeLock.RUnlock()
+	defer p.sending.Done()
+
 	select {
-	case <-time.After(time.Second * 3):
-		log.Warningf("交易 %s 处理超时", data.Signature)
 	case p.Pip <- data:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("交易 %s 未被读取: %w", data.Signature, ctx.Err())
+	case <-p.ctx.Done():
+		return fmt.Errorf("交易 %s 未被读取: %w", data.Signature, p.ctx.Err())
+	case <-p.abort:
+		return fmt.Errorf("交易 %s 未被读取: %w", data.Signature, ErrPoolMonitClosed)
+	}
+}
+
+// Close 停止写入并关闭 Pip, 之后可以安全地 range Pip
+//
+// 会等待正在写入的数据被读取, ctx 结束时放弃这些数据并返回错误, 可以重复调用
+func (p *PoolMonit) Close(ctx context.Context) error {
+	p.closeLock.Lock()
+	if p.closed {
+		p.closeLock.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.closeLock.Unlock()
+
+	err := waitContext(ctx, p.sending.Wait)
+	if err != nil {
+		close(p.abort)
+		p.sending.Wait()
+		err = fmt.Errorf("关闭超时, 丢弃未读取的数据: %w", err)
 	}
+	close(p.Pip)
+	return err
 }
@@ -0,0 +1,152 @@
+package bonk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-enols/gosolana/ws"
+)
+
+var ErrReconnectExhausted = errors.New("连续重连失败次数超出上限")
+
+// ConnState 连接状态
+type ConnState int
+
+const (
+	ConnStateIdle         ConnState = iota // 尚未启动
+	ConnStateConnecting                    // 正在建立连接
+	ConnStateConnected                     // 订阅成功
+	ConnStateDisconnected                  // 连接断开, 等待重连
+	ConnStateFailed                        // 重连失败次数超出上限, 不再重连
+	ConnStateClosed                        // 主动停止
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnStateIdle:
+		return "Idle"
+	case ConnStateConnecting:
+		return "Connecting"
+	case ConnStateConnected:
+		return "Connected"
+	case ConnStateDisconnected:
+		return "Disconnected"
+	case ConnStateFailed:
+		return "Failed"
+	case ConnStateClosed:
+		return "Closed"
+	default:
+		return ""
+	}
+}
+
+// ConnEvent 连接状态变化事件
+type ConnEvent struct {
+	Endpoint string        // websocket 地址
+	From     ConnState     // 变化前的状态
+	State    ConnState     // 变化后的状态
+	Attempt  int           // 连续失败次数
+	Delay    time.Duration // Disconnected 时距离下一次重连的等待时间
+	Err      error         // 导致断开或失败的错误
+	Time     time.Time
+}
+
+// ConnStateHandler 连接状态变化回调, 在监听协程中同步调用, 不要阻塞
+type ConnStateHandler func(ConnEvent)
+
+// ReconnectPolicy 重连策略, 指数退避并加入随机抖动
+type ReconnectPolicy struct {
+	InitialDelay time.Duration // 第一次重连前的等待时间
+	MaxDelay     time.Duration // 最大等待时间
+	Multiplier   float64       // 每次失败后等待时间的倍数
+	Jitter       float64       // 随机抖动比例, 0.2 表示在 ±20% 之间浮动
+	MaxFailures  int           // 连续失败次数上限, 0 表示无限重试
+	ResetAfter   time.Duration // 连接保持超过该时间后才清零失败次数, 避免连上即断时不停快速重连
+}
+
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialDelay: 500 * time.Millisecond,
+	MaxDelay:     30 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+	ResetAfter:   time.Minute,
+}
+
+// withDefaults 使用默认值填充未设置的字段
+func (p ReconnectPolicy) withDefaults() ReconnectPolicy {
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = DefaultReconnectPolicy.InitialDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultReconnectPolicy.MaxDelay
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultReconnectPolicy.Multiplier
+	}
+	if p.ResetAfter < 0 {
+		p.ResetAfter = 0
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	return p
+}
+
+// Delay 第 attempt 次连续失败后的等待时间, attempt 从 1 开始
+func (p ReconnectPolicy) Delay(attempt int) time.Duration {
+	p = p.withDefaults()
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay *= 1 - p.Jitter + 2*p.Jitter*rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// exhausted 连续失败次数是否超出上限
+func (p ReconnectPolicy) exhausted(failures int) bool {
+	return p.MaxFailures > 0 && failures >= p.MaxFailures
+}
+
+// dialWs 拨号建立websocket连接, 返回的 client 归端点所有, 只有 err 为 nil 时才持有连接
+//
+// 拨号使用独立的 ctx 并在返回前结束: ws.Client 读取出错后内部的重连看到 ctx 已结束不会再拨号,
+// 连接的关闭和重新拨号都由 supervise 负责, 不与 ws.Client 自身的重连共用同一个 client。
+// 拨号不受 ctx 控制, 最长等待 ws.DefaultHandshakeTimeout; 拨号期间 ctx 结束时关闭连接并返回错误
+func dialWs(ctx context.Context, url, proxy string) (*ws.Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dialCtx, cancel := context.WithCancel(context.Background())
+	client, err := ws.ConnectWithOptions(dialCtx, url, &ws.Options{
+		Proxy: proxy,
+	})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// closeWsClient 关闭 dialWs 建立的websocket连接, 重复关闭只会返回错误, 不需要处理
+func closeWsClient(client *ws.Client) {
+	if client == nil {
+		return
+	}
+	client.Close()
+}
@@ -0,0 +1,75 @@
+package bonk
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+)
+
+// FindAssociatedTokenAddress 获取钱包在代币下的ATA地址, 兼容 Token-2022
+func FindAssociatedTokenAddress(owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{
+			owner.Bytes(),
+			tokenProgram.Bytes(),
+			mint.Bytes(),
+		},
+		solana.SPLAssociatedTokenAccountProgramID,
+	)
+}
+
+// newCreateAssociatedTokenAccountInstruction 创建ATA, 使用 CreateIdempotent 账户已存在时不会失败
+func newCreateAssociatedTokenAccountInstruction(payer, owner, mint, tokenProgram solana.PublicKey) (solana.Instruction, error) {
+	ata, _, err := FindAssociatedTokenAddress(owner, mint, tokenProgram)
+	if err != nil {
+		return nil, err
+	}
+	return solana.NewInstruction(
+		solana.SPLAssociatedTokenAccountProgramID,
+		solana.AccountMetaSlice{
+			solana.Meta(payer).WRITE().SIGNER(),
+			solana.Meta(ata).WRITE(),
+			solana.Meta(owner),
+			solana.Meta(mint),
+			solana.Meta(solana.SystemProgramID),
+			solana.Meta(tokenProgram),
+		},
+		[]byte{1}, // CreateIdempotent
+	), nil
+}
+
+// newCloseAccountInstruction 关闭代币账户并将租金退回 destination, 兼容 Token-2022
+//
+// 关闭 WSOL 账户时会将其中全部 WSOL 解包为 SOL
+func newCloseAccountInstruction(tokenProgram, account, destination, owner solana.PublicKey) solana.Instruction {
+	return solana.NewInstruction(
+		tokenProgram,
+		solana.AccountMetaSlice{
+			solana.Meta(account).WRITE(),
+			solana.Meta(destination).WRITE(),
+			solana.Meta(owner).SIGNER(),
+		},
+		[]byte{token.Instruction_CloseAccount},
+	)
+}
+
+// newWrapSolInstructions 向 WSOL 账户转入 lamports 并同步余额
+func newWrapSolInstructions(owner, wsolAccount solana.PublicKey, lamports uint64) []solana.Instruction {
+	return []solana.Instruction{
+		system.NewTransferInstruction(lamports, owner, wsolAccount).Build(),
+		token.NewSyncNativeInstruction(wsolAccount).Build(),
+	}
+}
+
+// tokenAccountAmount 读取代币账户的余额, Token-2022 账户的前 165 字节与 Token 相同
+//
+// 布局: mint(32) owner(32) amount(8) ...
+func tokenAccountAmount(data []byte) (uint64, error) {
+	if len(data) < 72 {
+		return 0, fmt.Errorf("代币账户数据长度不足: %d", len(data))
+	}
+	return binary.LittleEndian.Uint64(data[64:72]), nil
+}
@@ -0,0 +1,518 @@
+package bonk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	raydium_launchpad "github.com/go-enols/go-bonk/idl"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/go-enols/go-log"
+	"github.com/go-enols/gosolana"
+)
+
+var ErrPoolNotTrading = errors.New("池子不在募集阶段, 无法在launchpad交易")
+
+// TradePool 交易需要的池子链上数据
+type TradePool struct {
+	PoolId            solana.PublicKey                  `json:"pool_id"`
+	PoolState         *raydium_launchpad.PoolState      `json:"pool_state"`
+	GlobalConfig      *raydium_launchpad.GlobalConfig   `json:"global_config"`
+	PlatformConfig    *raydium_launchpad.PlatformConfig `json:"platform_config"`
+	BaseTokenProgram  solana.PublicKey                  `json:"base_token_program"`
+	QuoteTokenProgram solana.PublicKey                  `json:"quote_token_program"`
+}
+
+// Quoter 使用池子当前状态创建报价器
+func (p *TradePool) Quoter(shareFeeRate uint64) (*Quoter, error) {
+	return NewQuoter(p.PoolState, p.GlobalConfig, p.PlatformConfig, shareFeeRate)
+}
+
+// TradeAccounts 生成 payer 交易该池子需要的全部账户
+func (p *TradePool) TradeAccounts(payer solana.PublicKey) (*TradeAccounts, error) {
+	pool := p.PoolState
+	authority, _, err := FindAuthorityPDA()
+	if err != nil {
+		return nil, err
+	}
+	eventAuthority, _, err := FindEventAuthorityPDA()
+	if err != nil {
+		return nil, err
+	}
+	baseVault, _, err := FindPoolVaultPDA(p.PoolId, pool.BaseMint)
+	if err != nil {
+		return nil, err
+	}
+	quoteVault, _, err := FindPoolVaultPDA(p.PoolId, pool.QuoteMint)
+	if err != nil {
+		return nil, err
+	}
+	userBaseToken, _, err := FindAssociatedTokenAddress(payer, pool.BaseMint, p.BaseTokenProgram)
+	if err != nil {
+		return nil, err
+	}
+	userQuoteToken, _, err := FindAssociatedTokenAddress(payer, pool.QuoteMint, p.QuoteTokenProgram)
+	if err != nil {
+		return nil, err
+	}
+	return &TradeAccounts{
+		Payer:             payer,
+		Authority:         authority,
+		GlobalConfig:      pool.GlobalConfig,
+		PlatformConfig:    pool.PlatformConfig,
+		PoolState:         p.PoolId,
+		UserBaseToken:     userBaseToken,
+		UserQuoteToken:    userQuoteToken,
+		BaseVault:         baseVault,
+		QuoteVault:        quoteVault,
+		BaseTokenMint:     pool.BaseMint,
+		QuoteTokenMint:    pool.QuoteMint,
+		BaseTokenProgram:  p.BaseTokenProgram,
+		QuoteTokenProgram: p.QuoteTokenProgram,
+		EventAuthority:    eventAuthority,
+		Program:           raydium_launchpad.ProgramID,
+	}, nil
+}
+
+// BuyExactIn 使用账户构建 buy_exact_in 指令
+func (a *TradeAccounts) BuyExactIn(amountIn, minimumAmountOut, shareFeeRate uint64) (solana.Instruction, error) {
+	return raydium_launchpad.NewBuyExactInInstruction(
+		amountIn, minimumAmountOut, shareFeeRate,
+		a.Payer, a.Authority, a.GlobalConfig, a.PlatformConfig, a.PoolState,
+		a.UserBaseToken, a.UserQuoteToken, a.BaseVault, a.QuoteVault,
+		a.BaseTokenMint, a.QuoteTokenMint, a.BaseTokenProgram, a.QuoteTokenProgram,
+		a.EventAuthority, a.Program,
+	)
+}
+
+// BuyExactOut 使用账户构建 buy_exact_out 指令
+func (a *TradeAccounts) BuyExactOut(amountOut, maximumAmountIn, shareFeeRate uint64) (solana.Instruction, error) {
+	return raydium_launchpad.NewBuyExactOutInstruction(
+		amountOut, maximumAmountIn, shareFeeRate,
+		a.Payer, a.Authority, a.GlobalConfig, a.PlatformConfig, a.PoolState,
+		a.UserBaseToken, a.UserQuoteToken, a.BaseVault, a.QuoteVault,
+		a.BaseTokenMint, a.QuoteTokenMint, a.BaseTokenProgram, a.QuoteTokenProgram,
+		a.EventAuthority, a.Program,
+	)
+}
+
+// SellExactIn 使用账户构建 sell_exact_in 指令
+func (a *TradeAccounts) SellExactIn(amountIn, minimumAmountOut, shareFeeRate uint64) (solana.Instruction, error) {
+	return raydium_launchpad.NewSellExactInInstruction(
+		amountIn, minimumAmountOut, shareFeeRate,
+		a.Payer, a.Authority, a.GlobalConfig, a.PlatformConfig, a.PoolState,
+		a.UserBaseToken, a.UserQuoteToken, a.BaseVault, a.QuoteVault,
+		a.BaseTokenMint, a.QuoteTokenMint, a.BaseTokenProgram, a.QuoteTokenProgram,
+		a.EventAuthority, a.Program,
+	)
+}
+
+// SellExactOut 使用账户构建 sell_exact_out 指令
+func (a *TradeAccounts) SellExactOut(amountOut, maximumAmountIn, shareFeeRate uint64) (solana.Instruction, error) {
+	return raydium_launchpad.NewSellExactOutInstruction(
+		amountOut, maximumAmountIn, shareFeeRate,
+		a.Payer, a.Authority, a.GlobalConfig, a.PlatformConfig, a.PoolState,
+		a.UserBaseToken, a.UserQuoteToken, a.BaseVault, a.QuoteVault,
+		a.BaseTokenMint, a.QuoteTokenMint, a.BaseTokenProgram, a.QuoteTokenProgram,
+		a.EventAuthority, a.Program,
+	)
+}
+
+// TradeResult 交易结果
+type TradeResult struct {
+	Signature solana.Signature `json:"signature"`
+	Quote     *Quote           `json:"quote"`     // 发送交易时的本地报价
+	Threshold uint64           `json:"threshold"` // minimum_amount_out 或 maximum_amount_in
+	Confirmed bool             `json:"confirmed"` // WaitConfirm 为 false 时始终为 false
+}
+
+// Trader launchpad 交易器, 从 base mint 出发完成账户推导、报价和签名发送
+type Trader struct {
+	*gosolana.Wallet
+	ctx context.Context
+
+	QuoteMint        solana.PublicKey // 报价代币, 默认 WSOL
+	SlippageBps      uint64           // 滑点, 默认 100 即 1%
+	ShareFeeRate     uint64           // 分享者手续费率, 大于0时需要设置 ShareFeeReceiver
+	ShareFeeReceiver solana.PublicKey // 分享者钱包, 手续费转入其 quote ATA
+	ComputeUnitLimit uint32           // 为0时不设置
+	ComputeUnitPrice uint64           // 优先费, 单位 micro lamports, 为0时不设置
+	WaitConfirm      bool             // 是否等待交易确认, 默认 true
+	UnwrapWsol       bool             // 卖出全部余额时是否关闭 WSOL ATA 解包, 包括账户中原有的 WSOL, 默认 true
+	Accounts         AccountLoader    // 读取账户, 设置为共享的 *RPC 时与其他组件的读取合并, 为 nil 时直接请求
+}
+
+func NewTrader(ctx context.Context, option ...gosolana.Option) (*Trader, error) {
+	wallet, err := gosolana.NewWallet(ctx, option...)
+	if err != nil {
+		return nil, err
+	}
+	return &Trader{
+		Wallet:           wallet,
+		ctx:              ctx,
+		QuoteMint:        solana.SolMint,
+		SlippageBps:      100,
+		ComputeUnitLimit: 200_000,
+		WaitConfirm:      true,
+		UnwrapWsol:       true,
+	}, nil
+}
+
+// LoadPool 获取 base mint 在 launchpad 上的池子及其配置
+func (t *Trader) LoadPool(baseMint solana.PublicKey) (*TradePool, error) {
+	poolId, _, err := FindPoolStatePDA(baseMint, t.QuoteMint)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := loadAccounts(t.ctx, t.Accounts, t.GetClient(), poolId, baseMint, t.QuoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("获取池子账户失败: %w", err)
+	}
+	if len(accounts) != 3 || accounts[0] == nil {
+		return nil, fmt.Errorf("池子 %s 不存在", poolId)
+	}
+	if accounts[1] == nil || accounts[2] == nil {
+		return nil, fmt.Errorf("代币 %s 或 %s 不存在", baseMint, t.QuoteMint)
+	}
+	poolState, err := raydium_launchpad.ParseAccount_PoolState(accounts[0].Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("解析池子状态失败: %w", err)
+	}
+
+	configs, err := loadAccounts(t.ctx, t.Accounts, t.GetClient(), poolState.GlobalConfig, poolState.PlatformConfig)
+	if err != nil {
+		return nil, fmt.Errorf("获取池子配置失败: %w", err)
+	}
+	if len(configs) != 2 || configs[0] == nil || configs[1] == nil {
+		return nil, fmt.Errorf("池子 %s 的配置不存在", poolId)
+	}
+	globalConfig, err := raydium_launchpad.ParseAccount_GlobalConfig(configs[0].Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("解析GlobalConfig失败: %w", err)
+	}
+	platformConfig, err := raydium_launchpad.ParseAccount_PlatformConfig(configs[1].Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("解析PlatformConfig失败: %w", err)
+	}
+
+	return &TradePool{
+		PoolId:            poolId,
+		PoolState:         poolState,
+		GlobalConfig:      globalConfig,
+		PlatformConfig:    platformConfig,
+		BaseTokenProgram:  accounts[1].Owner,
+		QuoteTokenProgram: accounts[2].Owner,
+	}, nil
+}
+
+// BuyExactIn 花费 amountIn 个 quote 买入 base, 按滑点计算 minimum_amount_out
+func (t *Trader) BuyExactIn(baseMint solana.PublicKey, amountIn uint64) (*TradeResult, error) {
+	pool, quoter, err := t.prepareTrade(baseMint)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := quoter.BuyExactIn(amountIn)
+	if err != nil {
+		return nil, err
+	}
+	minimumAmountOut := quote.MinimumAmountOut(t.SlippageBps)
+	return t.trade(pool, quote, minimumAmountOut, amountIn, false, func(accounts *TradeAccounts) (solana.Instruction, error) {
+		return accounts.BuyExactIn(amountIn, minimumAmountOut, t.ShareFeeRate)
+	})
+}
+
+// BuyExactOut 买入 amountOut 个 base, 按滑点计算 maximum_amount_in
+func (t *Trader) BuyExactOut(baseMint solana.PublicKey, amountOut uint64) (*TradeResult, error) {
+	pool, quoter, err := t.prepareTrade(baseMint)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := quoter.BuyExactOut(amountOut)
+	if err != nil {
+		return nil, err
+	}
+	if quote.AmountOut < amountOut {
+		return nil, fmt.Errorf("池子剩余可买数量不足: %d < %d", quote.AmountOut, amountOut)
+	}
+	maximumAmountIn := quote.MaximumAmountIn(t.SlippageBps)
+	return t.trade(pool, quote, maximumAmountIn, maximumAmountIn, false, func(accounts *TradeAccounts) (solana.Instruction, error) {
+		return accounts.BuyExactOut(amountOut, maximumAmountIn, t.ShareFeeRate)
+	})
+}
+
+// SellExactIn 卖出 amountIn 个 base, 按滑点计算 minimum_amount_out
+//
+// amountIn 等于钱包全部余额时, 在同一笔交易中关闭 base ATA 回收租金
+func (t *Trader) SellExactIn(baseMint solana.PublicKey, amountIn uint64) (*TradeResult, error) {
+	pool, quoter, err := t.prepareTrade(baseMint)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := t.BaseBalance(pool)
+	if err != nil {
+		return nil, err
+	}
+	if amountIn == 0 || amountIn > balance {
+		return nil, fmt.Errorf("代币余额不足: %d > %d", amountIn, balance)
+	}
+	return t.sellExactIn(pool, quoter, amountIn, amountIn == balance)
+}
+
+// SellPercent 按比例卖出钱包中的 base, percent 取值 (0, 100], 精度为 0.01%
+//
+// percent 为 100 时卖出全部余额并关闭 base ATA
+func (t *Trader) SellPercent(baseMint solana.PublicKey, percent float64) (*TradeResult, error) {
+	if percent <= 0 || percent > 100 {
+		return nil, fmt.Errorf("卖出比例无效: %v", percent)
+	}
+	pool, quoter, err := t.prepareTrade(baseMint)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := t.BaseBalance(pool)
+	if err != nil {
+		return nil, err
+	}
+	amountIn := balance
+	if percent < 100 {
+		amount := new(big.Int).Mul(u64(balance), u64(uint64(percent*100)))
+		amountIn = amount.Quo(amount, u64(BpsDenominator)).Uint64()
+	}
+	if amountIn == 0 {
+		return nil, fmt.Errorf("代币余额不足: %d", balance)
+	}
+	return t.sellExactIn(pool, quoter, amountIn, amountIn == balance)
+}
+
+// SellExactOut 卖出获得 amountOut 个 quote, 按滑点计算 maximum_amount_in
+//
+// maximum_amount_in 不会超过钱包余额
+func (t *Trader) SellExactOut(baseMint solana.PublicKey, amountOut uint64) (*TradeResult, error) {
+	pool, quoter, err := t.prepareTrade(baseMint)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := t.BaseBalance(pool)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := quoter.SellExactOut(amountOut)
+	if err != nil {
+		return nil, err
+	}
+	if quote.AmountIn > balance {
+		return nil, fmt.Errorf("代币余额不足: %d > %d", quote.AmountIn, balance)
+	}
+	maximumAmountIn := min(quote.MaximumAmountIn(t.SlippageBps), balance)
+	return t.trade(pool, quote, maximumAmountIn, 0, false, func(accounts *TradeAccounts) (solana.Instruction, error) {
+		return accounts.SellExactOut(amountOut, maximumAmountIn, t.ShareFeeRate)
+	})
+}
+
+func (t *Trader) sellExactIn(pool *TradePool, quoter *Quoter, amountIn uint64, closeBase bool) (*TradeResult, error) {
+	quote, err := quoter.SellExactIn(amountIn)
+	if err != nil {
+		return nil, err
+	}
+	minimumAmountOut := quote.MinimumAmountOut(t.SlippageBps)
+	return t.trade(pool, quote, minimumAmountOut, 0, closeBase, func(accounts *TradeAccounts) (solana.Instruction, error) {
+		return accounts.SellExactIn(amountIn, minimumAmountOut, t.ShareFeeRate)
+	})
+}
+
+// BaseBalance 获取钱包在池子 base 代币上的余额, ATA 不存在时返回 0
+//
+// 直接读取 ATA 账户数据, 账户不属于池子的 base 代币程序时返回错误
+func (t *Trader) BaseBalance(pool *TradePool) (uint64, error) {
+	ata, _, err := FindAssociatedTokenAddress(t.PublicKey(), pool.PoolState.BaseMint, pool.BaseTokenProgram)
+	if err != nil {
+		return 0, err
+	}
+	accounts, err := loadAccounts(t.ctx, t.Accounts, t.GetClient(), ata)
+	if err != nil {
+		return 0, fmt.Errorf("获取代币余额失败: %w", err)
+	}
+	if len(accounts) != 1 || accounts[0] == nil {
+		return 0, nil
+	}
+	if !accounts[0].Owner.Equals(pool.BaseTokenProgram) {
+		return 0, fmt.Errorf("代币账户 %s 不属于 %s", ata, pool.BaseTokenProgram)
+	}
+	amount, err := tokenAccountAmount(accountData(accounts[0]))
+	if err != nil {
+		return 0, fmt.Errorf("解析代币余额失败: %w", err)
+	}
+	return amount, nil
+}
+
+// prepareTrade 获取池子并检查是否可以交易
+func (t *Trader) prepareTrade(baseMint solana.PublicKey) (*TradePool, *Quoter, error) {
+	pool, err := t.LoadPool(baseMint)
+	if err != nil {
+		return nil, nil, err
+	}
+	if raydium_launchpad.PoolStatus(pool.PoolState.Status) != raydium_launchpad.PoolStatus_Fund {
+		return nil, nil, ErrPoolNotTrading
+	}
+	if t.ShareFeeRate > 0 && t.ShareFeeReceiver.IsZero() {
+		return nil, nil, errors.New("设置了分享者手续费率但没有设置 ShareFeeReceiver")
+	}
+	if t.ShareFeeRate > pool.GlobalConfig.MaxShareFeeRate {
+		return nil, nil, fmt.Errorf("分享者手续费率超出上限: %d > %d", t.ShareFeeRate, pool.GlobalConfig.MaxShareFeeRate)
+	}
+	quoter, err := pool.Quoter(t.ShareFeeRate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pool, quoter, nil
+}
+
+// trade 组装交易并发送
+//
+// 交易结构: 计算预算 -> 创建缺失的ATA -> 包装 WSOL -> 买入/卖出 -> 关闭 base 账户 -> 关闭 WSOL 账户
+//
+// wrapAmount 为买入时需要包装的 SOL 数量(quote 为 WSOL 时), closeBase 为卖出全部余额时关闭 base ATA 回收租金.
+// 本交易创建的 WSOL 账户总是关闭; 钱包原有的 WSOL 账户只在卖出全部余额且 UnwrapWsol 时关闭,
+// 连同原有余额一起解包为 SOL, 其他情况下保持不变, 卖出获得的 WSOL 留在该账户中
+func (t *Trader) trade(pool *TradePool, quote *Quote, threshold, wrapAmount uint64, closeBase bool, build func(*TradeAccounts) (solana.Instruction, error)) (*TradeResult, error) {
+	payer := t.PublicKey()
+	accounts, err := pool.TradeAccounts(payer)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions := t.computeBudgetInstructions()
+	create, quoteCreated, err := t.createMissingAccounts(pool, accounts.UserBaseToken, accounts.UserQuoteToken)
+	if err != nil {
+		return nil, err
+	}
+	instructions = append(instructions, create...)
+
+	wrapped := accounts.QuoteTokenMint.Equals(solana.SolMint)
+	if wrapped && wrapAmount > 0 {
+		instructions = append(instructions, newWrapSolInstructions(payer, accounts.UserQuoteToken, wrapAmount)...)
+	}
+
+	instruction, err := build(accounts)
+	if err != nil {
+		return nil, fmt.Errorf("构建交易指令失败: %w", err)
+	}
+	instruction, err = t.withShareFeeReceiver(instruction, pool)
+	if err != nil {
+		return nil, err
+	}
+	instructions = append(instructions, instruction)
+
+	if closeBase {
+		instructions = append(instructions, newCloseAccountInstruction(accounts.BaseTokenProgram, accounts.UserBaseToken, payer, payer))
+	}
+	if wrapped && (quoteCreated || closeBase && t.UnwrapWsol) {
+		instructions = append(instructions, newCloseAccountInstruction(accounts.QuoteTokenProgram, accounts.UserQuoteToken, payer, payer))
+	}
+	return t.send(instructions, quote, threshold)
+}
+
+// computeBudgetInstructions 设置计算单元上限和优先费
+func (t *Trader) computeBudgetInstructions() []solana.Instruction {
+	var instructions []solana.Instruction
+	if t.ComputeUnitLimit > 0 {
+		instructions = append(instructions, computebudget.NewSetComputeUnitLimitInstruction(t.ComputeUnitLimit).Build())
+	}
+	if t.ComputeUnitPrice > 0 {
+		instructions = append(instructions, computebudget.NewSetComputeUnitPriceInstruction(t.ComputeUnitPrice).Build())
+	}
+	return instructions
+}
+
+// createMissingAccounts 为不存在的 base/quote ATA 生成创建指令, quoteCreated 表示 quote ATA 由本交易创建
+func (t *Trader) createMissingAccounts(pool *TradePool, userBaseToken, userQuoteToken solana.PublicKey) (instructions []solana.Instruction, quoteCreated bool, err error) {
+	accounts, err := loadAccounts(t.ctx, t.Accounts, t.GetClient(), userBaseToken, userQuoteToken)
+	if err != nil {
+		return nil, false, fmt.Errorf("获取用户代币账户失败: %w", err)
+	}
+	if len(accounts) != 2 {
+		return nil, false, errors.New("获取用户代币账户失败: 返回数量不一致")
+	}
+
+	payer := t.PublicKey()
+	if accounts[0] == nil {
+		instruction, err := newCreateAssociatedTokenAccountInstruction(payer, payer, pool.PoolState.BaseMint, pool.BaseTokenProgram)
+		if err != nil {
+			return nil, false, err
+		}
+		instructions = append(instructions, instruction)
+	}
+	if accounts[1] == nil {
+		instruction, err := newCreateAssociatedTokenAccountInstruction(payer, payer, pool.PoolState.QuoteMint, pool.QuoteTokenProgram)
+		if err != nil {
+			return nil, false, err
+		}
+		instructions = append(instructions, instruction)
+		quoteCreated = true
+	}
+	return instructions, quoteCreated, nil
+}
+
+// withShareFeeReceiver 设置分享者时, 将其 quote ATA 作为 remaining account 追加到交易指令
+func (t *Trader) withShareFeeReceiver(instruction solana.Instruction, pool *TradePool) (solana.Instruction, error) {
+	if t.ShareFeeRate == 0 || t.ShareFeeReceiver.IsZero() {
+		return instruction, nil
+	}
+	shareToken, _, err := FindAssociatedTokenAddress(t.ShareFeeReceiver, pool.PoolState.QuoteMint, pool.QuoteTokenProgram)
+	if err != nil {
+		return nil, err
+	}
+	data, err := instruction.Data()
+	if err != nil {
+		return nil, err
+	}
+	accounts := append(instruction.Accounts(), solana.Meta(shareToken).WRITE())
+	return solana.NewInstruction(instruction.ProgramID(), accounts, data), nil
+}
+
+// send 签名并发送交易
+func (t *Trader) send(instructions []solana.Instruction, quote *Quote, threshold uint64) (*TradeResult, error) {
+	client := rpcClient(t.Accounts, t.GetClient())
+	recent, err := client.GetLatestBlockhash(t.ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("获取Hash失败: %w", err)
+	}
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(t.PublicKey()))
+	if err != nil {
+		return nil, fmt.Errorf("构建交易失败: %w", err)
+	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(t.PublicKey()) {
+			return &t.Wallet.PrivateKey
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("签名交易失败: %w", err)
+	}
+
+	signature, err := client.SendTransactionWithOpts(t.ctx, tx, rpc.TransactionOpts{
+		PreflightCommitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("发送交易失败: %w", ParseTransactionError(err))
+	}
+	log.Info(fmt.Sprintf("交易已发送: %s", signature))
+
+	result := &TradeResult{
+		Signature: signature,
+		Quote:     quote,
+		Threshold: threshold,
+	}
+	if t.WaitConfirm {
+		confirmed, err := t.Wallet.GetTransaction(t.ctx, signature, rpc.CommitmentConfirmed)
+		if err != nil {
+			return result, fmt.Errorf("等待交易确认失败: %w", err)
+		}
+		result.Confirmed = confirmed
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package bonk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DefaultTransactionRetry 刚收到日志时交易往往还无法通过 getTransaction 获取, 按该策略重试
+var DefaultTransactionRetry = ReconnectPolicy{
+	InitialDelay: 300 * time.Millisecond,
+	MaxDelay:     5 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+	MaxFailures:  6,
+}
+
+// TransactionStats 获取交易的统计
+type TransactionStats struct {
+	Fetched   uint64 `json:"fetched"`   // 获取成功的交易数量
+	Retried   uint64 `json:"retried"`   // 获取成功但第一次不存在、需要重试的交易数量
+	Retries   uint64 `json:"retries"`   // 重试的总次数
+	Fallbacks uint64 `json:"fallbacks"` // 使用 FallbackCommitment 才获取成功的交易数量
+	NotFound  uint64 `json:"not_found"` // 重试用尽仍不存在的交易数量
+}
+
+type transactionStats struct {
+	fetched   atomic.Uint64
+	retried   atomic.Uint64
+	retries   atomic.Uint64
+	fallbacks atomic.Uint64
+	notFound  atomic.Uint64
+}
+
+func (s *transactionStats) snapshot() TransactionStats {
+	return TransactionStats{
+		Fetched:   s.fetched.Load(),
+		Retried:   s.retried.Load(),
+		Retries:   s.retries.Load(),
+		Fallbacks: s.fallbacks.Load(),
+		NotFound:  s.notFound.Load(),
+	}
+}
+
+// transactionFetcher 获取交易, 交易不存在时按退避策略重试并回退到另一个提交级别
+type transactionFetcher struct {
+	client     *rpc.Client
+	commitment rpc.CommitmentType
+	fallback   rpc.CommitmentType // 为空或与 commitment 相同时不回退
+	policy     ReconnectPolicy
+	stats      *transactionStats
+}
+
+func (f *transactionFetcher) fetch(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, error) {
+	commitments := []rpc.CommitmentType{f.commitment}
+	if f.fallback != "" && f.fallback != f.commitment {
+		commitments = append(commitments, f.fallback)
+	}
+	policy := f.policy.withDefaults()
+	if policy.MaxFailures <= 0 {
+		policy.MaxFailures = DefaultTransactionRetry.MaxFailures
+	}
+
+	retries := 0
+	for i, commitment := range commitments {
+		for attempt := 1; ; attempt++ {
+			transaction, err := f.client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+				Commitment:                     commitment,
+				MaxSupportedTransactionVersion: &Verison,
+			})
+			if err == nil && transaction != nil && transaction.Transaction != nil && transaction.Meta != nil {
+				f.stats.fetched.Add(1)
+				if retries > 0 {
+					f.stats.retried.Add(1)
+				}
+				if i > 0 {
+					f.stats.fallbacks.Add(1)
+				}
+				return transaction, nil
+			}
+			if err != nil && !errors.Is(err, rpc.ErrNotFound) {
+				return nil, fmt.Errorf("获取交易失败: %w", err)
+			}
+			if policy.exhausted(attempt) {
+				break
+			}
+
+			retries++
+			f.stats.retries.Add(1)
+			timer := time.NewTimer(policy.Delay(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, fmt.Errorf("获取交易失败: %w", ctx.Err())
+			case <-timer.C:
+			}
+		}
+	}
+	f.stats.notFound.Add(1)
+	return nil, fmt.Errorf("获取交易失败: 重试 %d 次后交易 %s 仍不存在: %w", retries, signature, rpc.ErrNotFound)
+}